@@ -0,0 +1,177 @@
+package plasmactlupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	archiveFormatTarGz = "tar.gz"
+	archiveFormatZip   = "zip"
+
+	defaultBinaryInArchiveTpl = "{{.Name}}{{.Ext}}"
+	archiveTmpSuffix          = ".archive"
+
+	// maxBinarySize limits the extracted binary size to protect from decompression bombs.
+	maxBinarySize = 1 << 30
+)
+
+var errBinaryNotInArchive = errors.New("binary not found in archive")
+
+// isArchive checks if releases are packaged in archives.
+func (u *updateAction) isArchive() bool {
+	return u.cfg.ArchiveFormat != ""
+}
+
+// binaryPathInArchive returns a cleaned relative path of the binary inside the archive.
+func (u *updateAction) binaryPathInArchive(version string) (string, error) {
+	tpl := u.cfg.BinaryPathInArchive
+	if tpl == "" {
+		tpl = defaultBinaryInArchiveTpl
+	}
+
+	p, err := formatTemplate(tpl, u.newTemplateVars(version))
+	if err != nil {
+		return "", fmt.Errorf("failed to format binary path in archive: %w", err)
+	}
+
+	return cleanArchivePath(p)
+}
+
+// cleanArchivePath normalizes an archive entry name and rejects paths escaping the archive root.
+func cleanArchivePath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(name) || filepath.IsAbs(name) || hasVolumeName(name) {
+		return "", fmt.Errorf("archive path %q must be relative", name)
+	}
+	cleaned := path.Clean(name)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("archive path %q points outside of archive", name)
+	}
+	return cleaned, nil
+}
+
+// hasVolumeName checks if the path starts with a windows drive letter, e.g. "C:".
+func hasVolumeName(name string) bool {
+	if len(name) < 2 || name[1] != ':' {
+		return false
+	}
+	c := name[0]
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// extractBinary extracts the binary from the downloaded archive to the temp path.
+func (u *updateAction) extractBinary(version string) error {
+	binPath, err := u.binaryPathInArchive(version)
+	if err != nil {
+		return err
+	}
+
+	u.Term().Printfln("Extracting %s from archive", binPath)
+	switch u.cfg.ArchiveFormat {
+	case archiveFormatTarGz:
+		return u.extractFromTarGz(binPath)
+	case archiveFormatZip:
+		return u.extractFromZip(binPath)
+	default:
+		return fmt.Errorf("unsupported archive format %q", u.cfg.ArchiveFormat)
+	}
+}
+
+func (u *updateAction) extractFromTarGz(binPath string) error {
+	f, err := os.Open(filepath.Clean(u.fDownloadPath))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: %s", errBinaryNotInArchive, binPath)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+
+		name, err := cleanArchivePath(hdr.Name)
+		if err != nil {
+			u.Log().Debug("skipping unsafe archive entry", "name", hdr.Name, "error", err)
+			continue
+		}
+		if name != binPath {
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return fmt.Errorf("archive entry %s is not a regular file", hdr.Name)
+		}
+
+		return writeExtracted(tr, u.fTmpPath, maxBinarySize)
+	}
+}
+
+func (u *updateAction) extractFromZip(binPath string) error {
+	zr, err := zip.OpenReader(u.fDownloadPath)
+	if err != nil {
+		return fmt.Errorf("failed to read zip archive: %w", err)
+	}
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		name, err := cleanArchivePath(zf.Name)
+		if err != nil {
+			u.Log().Debug("skipping unsafe archive entry", "name", zf.Name, "error", err)
+			continue
+		}
+		if name != binPath {
+			continue
+		}
+		if !zf.Mode().IsRegular() {
+			return fmt.Errorf("archive entry %s is not a regular file", zf.Name)
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		return writeExtracted(rc, u.fTmpPath, maxBinarySize)
+	}
+
+	return fmt.Errorf("%w: %s", errBinaryNotInArchive, binPath)
+}
+
+// writeExtracted writes the archive entry to dst limiting its size.
+func writeExtracted(r io.Reader, dst string, limit int64) error {
+	out, err := os.OpenFile(filepath.Clean(dst), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	n, err := io.Copy(out, io.LimitReader(r, limit+1))
+	if err != nil {
+		return err
+	}
+	if n > limit {
+		return fmt.Errorf("extracted binary exceeds %d bytes", limit)
+	}
+
+	return nil
+}
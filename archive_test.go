@@ -0,0 +1,218 @@
+package plasmactlupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanArchivePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"app", "app", false},
+		{"./app", "app", false},
+		{"dist/bin/app", "dist/bin/app", false},
+		{"dist//bin/./app", "dist/bin/app", false},
+		{"a/../app", "app", false},
+		{"dist\\bin\\app", "dist/bin/app", false},
+		{"..", "", true},
+		{"../x", "", true},
+		{"a/../../b", "", true},
+		{"..\\x", "", true},
+		{"a\\..\\..\\b", "", true},
+		{"/abs", "", true},
+		{"\\abs", "", true},
+		{"C:\\Windows\\app.exe", "", true},
+		{"c:/app", "", true},
+		{".", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cleanArchivePath(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("cleanArchivePath(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("cleanArchivePath(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// archiveEntry is a test archive entry, a symlink if link is set.
+type archiveEntry struct {
+	name, body, link string
+}
+
+func writeTarGz(t *testing.T, path string, entries []archiveEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0755, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		if e.link != "" {
+			hdr = &tar.Header{Name: e.name, Mode: 0777, Linkname: e.link, Typeflag: tar.TypeSymlink}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if e.link == "" {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, path string, entries []archiveEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		fh := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		body := e.body
+		fh.SetMode(0755)
+		if e.link != "" {
+			fh.SetMode(os.ModeSymlink | 0777)
+			body = e.link
+		}
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		binPath string
+		entries []archiveEntry
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "binary in root",
+			entries: []archiveEntry{{name: "README.md", body: "readme"}, {name: "app", body: "binary"}},
+			want:    "binary",
+		},
+		{
+			name:    "binary in subdir",
+			binPath: "app_{{.OS}}/bin/{{.Name}}",
+			entries: []archiveEntry{{name: "app_Linux/bin/app", body: "binary"}},
+			want:    "binary",
+		},
+		{
+			name:    "traversal entries are skipped",
+			entries: []archiveEntry{{name: "../app", body: "evil"}, {name: "/app", body: "evil"}, {name: "app", body: "binary"}},
+			want:    "binary",
+		},
+		{
+			name:    "only traversal entry",
+			entries: []archiveEntry{{name: "x/../../app", body: "evil"}},
+			wantErr: errBinaryNotInArchive.Error(),
+		},
+		{
+			name:    "symlink entry",
+			entries: []archiveEntry{{name: "app", link: "/usr/bin/evil"}},
+			wantErr: "is not a regular file",
+		},
+		{
+			name:    "missing binary",
+			entries: []archiveEntry{{name: "other", body: "binary"}},
+			wantErr: errBinaryNotInArchive.Error(),
+		},
+		{
+			name:    "binary path outside of archive",
+			binPath: "../{{.Name}}",
+			entries: []archiveEntry{{name: "app", body: "binary"}},
+			wantErr: "points outside of archive",
+		},
+	}
+
+	formats := map[string]func(*testing.T, string, []archiveEntry){
+		archiveFormatTarGz: writeTarGz,
+		archiveFormatZip:   writeZip,
+	}
+	for format, write := range formats {
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				dir := t.TempDir()
+				u := &updateAction{
+					appName:       "app",
+					os:            "Linux",
+					cfg:           &config{ArchiveFormat: format, BinaryPathInArchive: tt.binPath},
+					fTmpPath:      filepath.Join(dir, "out", "app"),
+					fDownloadPath: filepath.Join(dir, "out", "app"+archiveTmpSuffix),
+				}
+				if err := os.Mkdir(filepath.Dir(u.fTmpPath), 0700); err != nil {
+					t.Fatal(err)
+				}
+				write(t, u.fDownloadPath, tt.entries)
+
+				err := u.extractBinary("1.0.0")
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				got, err := os.ReadFile(u.fTmpPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != tt.want {
+					t.Fatalf("extracted %q, want %q", got, tt.want)
+				}
+				if _, err = os.Stat(filepath.Join(dir, "app")); !errors.Is(err, os.ErrNotExist) {
+					t.Fatalf("file extracted outside of temp path: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestWriteExtractedLimit(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "app")
+
+	if err := writeExtracted(strings.NewReader("12345678"), dst, 8); err != nil {
+		t.Fatalf("unexpected error at the limit: %v", err)
+	}
+	err := writeExtracted(strings.NewReader("123456789"), dst, 8)
+	if err == nil || !strings.Contains(err.Error(), "exceeds 8 bytes") {
+		t.Fatalf("expected size limit error, got %v", err)
+	}
+}
@@ -0,0 +1,474 @@
+package plasmactlupdate
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/launchrctl/keyring"
+)
+
+const (
+	authBasic  = "basic"
+	authBearer = "bearer"
+	authHeader = "header"
+	authNetrc  = "netrc"
+)
+
+// errNoCredentials is returned when credentials are missing in non-interactive mode.
+var errNoCredentials = errors.New("credentials are required but not provided in non-interactive mode")
+
+// authConfig is a configuration of repository authentication.
+type authConfig struct {
+	Type           string `yaml:"type"`
+	Header         string `yaml:"header"`
+	UsernameEnv    string `yaml:"username_env"`
+	PasswordEnv    string `yaml:"password_env"`
+	TokenEnv       string `yaml:"token_env"`
+	NonInteractive bool   `yaml:"non_interactive"`
+}
+
+func validateAuthConfig(cfg authConfig) error {
+	switch cfg.Type {
+	case "", authBasic, authBearer, authNetrc:
+	case authHeader:
+		if cfg.Header == "" {
+			return fmt.Errorf("field 'auth.header' is required for %s auth", authHeader)
+		}
+	default:
+		return fmt.Errorf("field 'auth.type' must be one of [%s, %s, %s, %s]", authBasic, authBearer, authHeader, authNetrc)
+	}
+	return nil
+}
+
+// resolveCredentials gets secrets required by the configured auth type.
+func (u *updateAction) resolveCredentials() error {
+	switch u.cfg.Auth.Type {
+	case authBearer, authHeader:
+		return u.getToken()
+	case authNetrc:
+		// Netrc entries are looked up per request host.
+		return nil
+	default:
+		// Get username and password.
+		return u.getCredentials()
+	}
+}
+
+// getToken gets an API token from user input, the environment or the keyring.
+// The token is kept as a password of the credentials item.
+func (u *updateAction) getToken() error {
+	if u.credentials.Password != "" {
+		return nil
+	}
+
+	ci, ok, err := u.lookupCredentials(u.credentials.URL)
+	if err != nil {
+		return err
+	}
+	if !ok || ci.Password == "" {
+		if u.nonInteractive {
+			return fmt.Errorf("%w: token for %s, set environment variable from 'auth.token_env' or use --token option", errNoCredentials, u.credentials.URL)
+		}
+		return fmt.Errorf("token for %s not found, use --token option, environment variable from 'auth.token_env' or add it to the keyring as a password", u.credentials.URL)
+	}
+
+	u.credentials = ci
+	return nil
+}
+
+// envCredentials reads credentials from the environment variables configured in auth.
+// Secrets are read from a file if the variable with "_FILE" suffix is set instead.
+func (u *updateAction) envCredentials(url string) (keyring.CredentialsItem, error) {
+	ci := keyring.CredentialsItem{URL: url}
+	var err error
+	if ci.Username, err = readSecretEnv(u.cfg.Auth.UsernameEnv); err != nil {
+		return ci, err
+	}
+	if ci.Password, err = readSecretEnv(u.cfg.Auth.PasswordEnv); err != nil {
+		return ci, err
+	}
+	if ci.Password == "" {
+		if ci.Password, err = readSecretEnv(u.cfg.Auth.TokenEnv); err != nil {
+			return ci, err
+		}
+	}
+	return ci, nil
+}
+
+// readSecretEnv returns a value of the environment variable or the content of the file from NAME_FILE variable.
+func readSecretEnv(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	if v := os.Getenv(name); v != "" {
+		return v, nil
+	}
+
+	p := os.Getenv(name + "_FILE")
+	if p == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(filepath.Clean(p))
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file from %s_FILE: %w", name, err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// lookupCredentials finds credentials for the URL in the options, the environment or the keyring.
+// The keyring isn't used in non-interactive mode.
+func (u *updateAction) lookupCredentials(url string) (keyring.CredentialsItem, bool, error) {
+	// Credentials submitted with --username, --password or --token options have priority.
+	if u.credentials.Password != "" {
+		ci := u.credentials
+		ci.URL = url
+		u.Log().Debug("using credentials from options", "url", url)
+		return ci, true, nil
+	}
+
+	ci, err := u.envCredentials(url)
+	if err != nil {
+		return ci, false, err
+	}
+	if ci.Password != "" {
+		u.Log().Debug("using credentials from environment", "url", url)
+		return ci, true, nil
+	}
+
+	if u.nonInteractive {
+		return ci, false, nil
+	}
+
+	ci, err = u.k.GetForURL(url)
+	if err != nil {
+		if errors.Is(err, keyring.ErrEmptyPass) {
+			return ci, false, err
+		} else if !errors.Is(err, keyring.ErrNotFound) {
+			return ci, false, errMalformedKeyring
+		}
+		return ci, false, nil
+	}
+
+	return ci, true, nil
+}
+
+// setAuth sets authentication of the request according to the configured auth type.
+// Credentials are sent only to the repository, e.g. not to CDN hosts of manifest artifacts.
+// Netrc entries are matched by host, so they're scoped on their own.
+func (u *updateAction) setAuth(req *http.Request) {
+	if u.cfg.Auth.Type != authNetrc && !u.isRepositoryURL(req.URL.String()) {
+		u.Log().Debug("skip auth for request outside of repository", "url", req.URL.Redacted())
+		return
+	}
+
+	switch u.cfg.Auth.Type {
+	case authBearer:
+		if u.credentials.Password != "" {
+			req.Header.Set("Authorization", "Bearer "+u.credentials.Password)
+		}
+	case authHeader:
+		if u.credentials.Password != "" {
+			req.Header.Set(u.cfg.Auth.Header, u.credentials.Password)
+		}
+	case authNetrc:
+		login, password, err := lookupNetrc(req.URL.Hostname())
+		if err != nil {
+			u.Log().Debug("netrc lookup failed", "host", req.URL.Hostname(), "error", err)
+			return
+		}
+		req.SetBasicAuth(login, password)
+	default:
+		if u.bearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+u.bearerToken)
+			return
+		}
+		// Only set auth if required and we have credentials
+		if u.requiresAuth && u.credentials.Username != "" && u.credentials.Password != "" {
+			req.SetBasicAuth(u.credentials.Username, u.credentials.Password)
+		}
+	}
+}
+
+// isRepositoryURL checks the URL belongs to the current repository URL.
+func (u *updateAction) isRepositoryURL(rawURL string) bool {
+	base := strings.TrimSuffix(u.credentials.URL, "/")
+	if base == "" {
+		return false
+	}
+	return rawURL == base || strings.HasPrefix(rawURL, base+"/")
+}
+
+// netrcPath returns a path of the netrc file.
+func netrcPath() (string, error) {
+	if p := os.Getenv("NETRC"); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".netrc"), nil
+}
+
+// lookupNetrc finds login and password for the host in the netrc file.
+func lookupNetrc(host string) (string, string, error) {
+	p, err := netrcPath()
+	if err != nil {
+		return "", "", err
+	}
+	f, err := os.Open(filepath.Clean(p))
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	return parseNetrc(f, host)
+}
+
+// parseNetrc finds login and password for the host, the "default" entry is used as a fallback.
+func parseNetrc(r io.Reader, host string) (string, string, error) {
+	type entry struct {
+		login, password string
+	}
+	var found, def *entry
+	var cur *entry
+
+	// key is a token waiting for its value, it may be on the next line.
+	var key string
+	macro := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Macro definition lasts until an empty line.
+		if macro {
+			macro = strings.TrimSpace(scanner.Text()) != ""
+			continue
+		}
+
+		for _, tok := range strings.Fields(scanner.Text()) {
+			if key != "" {
+				switch key {
+				case "machine":
+					cur = nil
+					if found == nil && tok == host {
+						found = &entry{}
+						cur = found
+					}
+				case "login":
+					if cur != nil {
+						cur.login = tok
+					}
+				case "password":
+					if cur != nil {
+						cur.password = tok
+					}
+				}
+				key = ""
+				continue
+			}
+
+			switch tok {
+			case "machine", "login", "password", "account":
+				key = tok
+			case "default":
+				cur = nil
+				if def == nil {
+					def = &entry{}
+					cur = def
+				}
+			case "macdef":
+				// Macros aren't supported, skip the definition.
+				cur = nil
+				macro = true
+			}
+			if macro {
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+
+	if found == nil {
+		found = def
+	}
+	if found == nil {
+		return "", "", fmt.Errorf("no netrc entry for %s", host)
+	}
+
+	return found.login, found.password, nil
+}
+
+// isExplicitAuth checks if the auth type is configured and must not be guessed.
+func (cfg *config) isExplicitAuth() bool {
+	return cfg.Auth.Type != ""
+}
+
+// negotiateAuth prepares auth from WWW-Authenticate challenge of the unauthorized response.
+// Basic challenges use username and password, Bearer challenges with a realm
+// exchange them for a token at the realm, otherwise the password is used as a token.
+func (u *updateAction) negotiateAuth(resp *http.Response) error {
+	ch := parseAuthChallenge(resp.Header.Get("WWW-Authenticate"))
+	u.Log().Debug("auth challenge", "url", resp.Request.URL.String(), "scheme", ch.scheme, "realm", ch.params["realm"])
+
+	switch ch.scheme {
+	case "", "basic":
+		u.requiresAuth = true
+		return u.getCredentials()
+	case "bearer":
+		u.requiresAuth = true
+		if err := u.getCredentials(); err != nil {
+			return err
+		}
+		if validateURL(ch.params["realm"]) != nil {
+			u.bearerToken = u.credentials.Password
+			return nil
+		}
+		token, err := u.fetchBearerToken(resp.Request.URL, ch, "", u.credentials.Username, u.credentials.Password)
+		if err != nil {
+			return err
+		}
+		u.bearerToken = token
+		return nil
+	default:
+		return fmt.Errorf("unsupported auth scheme %q requested by %s", ch.scheme, resp.Request.URL.Host)
+	}
+}
+
+// fetchBearerToken requests a token from the realm of Bearer challenge sent by the origin.
+// The scope of the challenge is used if scope is empty.
+// Credentials are sent only to a realm trusted by [trustedRealm], otherwise an anonymous token is requested.
+func (u *updateAction) fetchBearerToken(origin *url.URL, ch authChallenge, scope, username, password string) (string, error) {
+	realm := ch.params["realm"]
+	if err := validateURL(realm); err != nil {
+		return "", fmt.Errorf("invalid token realm: %w", err)
+	}
+	realmURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm: %w", err)
+	}
+
+	q := url.Values{}
+	if service := ch.params["service"]; service != "" {
+		q.Set("service", service)
+	}
+	if scope == "" {
+		scope = ch.params["scope"]
+	}
+	if scope != "" {
+		q.Set("scope", scope)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, realm+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" && password != "" {
+		if err = trustedRealm(origin, realmURL); err != nil {
+			u.Term().Warning().Printfln("Credentials aren't sent to the token service: %s", err)
+		} else {
+			req.SetBasicAuth(username, password)
+		}
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err = u.checkResponseStatus(resp); err != nil {
+		return "", err
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse token response: %w", err)
+	}
+
+	token := body.Token
+	if token == "" {
+		token = body.AccessToken
+	}
+	if token == "" {
+		return "", fmt.Errorf("token service returned an empty token")
+	}
+
+	return token, nil
+}
+
+// trustedRealm checks credentials may be sent to the token realm requested by the origin.
+// The realm must use https unless the origin is plain http itself, and belong to the origin site.
+func trustedRealm(origin, realm *url.URL) error {
+	if realm.Scheme != "https" && origin.Scheme != "http" {
+		return fmt.Errorf("realm %s doesn't use https", realm.Redacted())
+	}
+	if !sameSite(strings.ToLower(origin.Hostname()), strings.ToLower(realm.Hostname())) {
+		return fmt.Errorf("realm host %s doesn't belong to %s", realm.Hostname(), origin.Hostname())
+	}
+	return nil
+}
+
+// sameSite checks the realm host is the origin host, its subdomain or a host under the same parent domain,
+// e.g. auth.docker.io for registry-1.docker.io. Top-level domains aren't considered a parent.
+func sameSite(host, realmHost string) bool {
+	if realmHost == host || strings.HasSuffix(realmHost, "."+host) {
+		return true
+	}
+	if net.ParseIP(host) != nil {
+		return false
+	}
+	_, parent, ok := strings.Cut(host, ".")
+	if !ok || !strings.Contains(parent, ".") {
+		return false
+	}
+	return realmHost == parent || strings.HasSuffix(realmHost, "."+parent)
+}
+
+// authChallenge is a parsed WWW-Authenticate header.
+type authChallenge struct {
+	scheme string
+	params map[string]string
+}
+
+// parseAuthChallenge parses a WWW-Authenticate header value, e.g.
+// Bearer realm="https://auth.example.com/token",service="registry".
+func parseAuthChallenge(header string) authChallenge {
+	ch := authChallenge{params: make(map[string]string)}
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	ch.scheme = strings.ToLower(scheme)
+
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key != "" {
+			ch.params[key] = strings.TrimSpace(value)
+		}
+	}
+
+	return ch
+}
@@ -0,0 +1,358 @@
+package plasmactlupdate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/launchrctl/keyring"
+)
+
+func TestSetAuthScope(t *testing.T) {
+	const repoURL = "https://repo.example.com/releases"
+
+	tests := []struct {
+		name   string
+		auth   authConfig
+		token  string
+		url    string
+		header string
+		want   string
+	}{
+		{"bearer in repository", authConfig{Type: authBearer}, "", repoURL + "/1.0.0/app", "Authorization", "Bearer secret"},
+		{"bearer repository root", authConfig{Type: authBearer}, "", repoURL, "Authorization", "Bearer secret"},
+		{"bearer on other host", authConfig{Type: authBearer}, "", "https://cdn.example.com/1.0.0/app", "Authorization", ""},
+		{"bearer on sibling path", authConfig{Type: authBearer}, "", repoURL + "-evil/app", "Authorization", ""},
+		{"header in repository", authConfig{Type: authHeader, Header: "X-Token"}, "", repoURL + "/app", "X-Token", "secret"},
+		{"header on other host", authConfig{Type: authHeader, Header: "X-Token"}, "", "https://cdn.example.com/app", "X-Token", ""},
+		{"negotiated token in repository", authConfig{}, "token", repoURL + "/app", "Authorization", "Bearer token"},
+		{"negotiated token on other host", authConfig{}, "token", "https://cdn.example.com/app", "Authorization", ""},
+		{"basic on other host", authConfig{}, "", "https://cdn.example.com/app", "Authorization", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &updateAction{
+				cfg:          &config{Auth: tt.auth},
+				requiresAuth: true,
+				bearerToken:  tt.token,
+				credentials: keyring.CredentialsItem{
+					URL:      repoURL + "/",
+					Username: "user",
+					Password: "secret",
+				},
+			}
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u.setAuth(req)
+			if got := req.Header.Get(tt.header); got != tt.want {
+				t.Errorf("%s header %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("header on redirect to another host", func(t *testing.T) {
+		cdn := &headerRecorder{body: "binary"}
+		cdnSrv := httptest.NewServer(cdn)
+		defer cdnSrv.Close()
+		repo := &headerRecorder{}
+		repoSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			repo.record(r)
+			http.Redirect(w, r, cdnSrv.URL+r.URL.Path, http.StatusFound)
+		}))
+		defer repoSrv.Close()
+
+		u := &updateAction{
+			cfg:         &config{Auth: authConfig{Type: authHeader, Header: "X-JFrog-Art-Api"}},
+			credentials: keyring.CredentialsItem{URL: repoSrv.URL, Password: "secret"},
+		}
+		u.source = &httpSource{u: u}
+		var err error
+		if u.client, err = u.newHTTPClient(); err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := u.sendRequest(repoSrv.URL + "/1.0.0/app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := repo.header("X-JFrog-Art-Api"); got != "secret" {
+			t.Errorf("repository got header %q, want secret", got)
+		}
+		if got := cdn.header("X-JFrog-Art-Api"); got != "" {
+			t.Errorf("redirect target got header %q", got)
+		}
+	})
+}
+
+func TestParseNetrc(t *testing.T) {
+	const netrc = `machine repo.example.com
+  login first
+  password first-pass
+machine repo.example.com login second password second-pass
+
+machine other.example.com account acct login other password other-pass
+macdef init
+machine macro.example.com login macro password macro-pass
+
+machine
+  after.example.com
+  login after
+  password after-pass
+default login anonymous password guest
+`
+
+	tests := []struct {
+		name      string
+		netrc     string
+		host      string
+		wantLogin string
+		wantPass  string
+		wantErr   bool
+	}{
+		{"first match wins", netrc, "repo.example.com", "first", "first-pass", false},
+		{"account is skipped", netrc, "other.example.com", "other", "other-pass", false},
+		{"macro body is skipped", netrc, "macro.example.com", "anonymous", "guest", false},
+		{"tokens after macro", netrc, "after.example.com", "after", "after-pass", false},
+		{"default fallback", netrc, "unknown.example.com", "anonymous", "guest", false},
+		{"no entry", "machine repo.example.com login user password pass\n", "unknown.example.com", "", "", true},
+		{"account named as token", "machine a.example.com account password login user password pass\n", "a.example.com", "user", "pass", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			login, pass, err := parseNetrc(strings.NewReader(tt.netrc), tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if login != tt.wantLogin || pass != tt.wantPass {
+				t.Errorf("got %q:%q, want %q:%q", login, pass, tt.wantLogin, tt.wantPass)
+			}
+		})
+	}
+}
+
+func TestSetAuthBasicInRepository(t *testing.T) {
+	u := &updateAction{
+		cfg:          &config{},
+		requiresAuth: true,
+		credentials: keyring.CredentialsItem{
+			URL:      "https://repo.example.com",
+			Username: "user",
+			Password: "secret",
+		},
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://repo.example.com/1.0.0/app", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.setAuth(req)
+	if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "secret" {
+		t.Errorf("basic auth %q:%q, want user:secret", user, pass)
+	}
+}
+
+func TestTrustedRealm(t *testing.T) {
+	tests := []struct {
+		origin  string
+		realm   string
+		wantErr bool
+	}{
+		{"https://repo.example.com/app", "https://repo.example.com/token", false},
+		{"https://registry-1.docker.io/v2/", "https://auth.docker.io/token", false},
+		{"https://registry.gitlab.com/v2/", "https://gitlab.com/jwt/auth", false},
+		{"https://example.com/v2/", "https://auth.example.com/token", false},
+		{"http://repo.example.com/app", "http://repo.example.com/token", false},
+		{"https://repo.example.com/app", "http://repo.example.com/token", true},
+		{"https://repo.example.com/app", "https://evil.com/token", true},
+		{"https://repo.example.co.uk/app", "https://evil.co.uk/token", true},
+		{"https://example.com/app", "https://evil.com/token", true},
+		{"https://10.0.0.1/app", "https://20.0.0.1/token", true},
+		{"https://10.0.0.1:8443/app", "https://10.0.0.1/token", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin+" "+tt.realm, func(t *testing.T) {
+			origin, err := url.Parse(tt.origin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			realm, err := url.Parse(tt.realm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = trustedRealm(origin, realm); (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseAuthChallenge(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantScheme string
+		wantParams map[string]string
+	}{
+		{"empty", "", "", map[string]string{}},
+		{"scheme only", "Basic", "basic", map[string]string{}},
+		{"quoted realm", `Basic realm="Artifactory Realm"`, "basic", map[string]string{"realm": "Artifactory Realm"}},
+		{
+			"bearer params",
+			`Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`,
+			"bearer",
+			map[string]string{"realm": "https://auth.docker.io/token", "service": "registry.docker.io"},
+		},
+		{
+			"scope with commas",
+			`Bearer realm="https://auth.example.com/token",scope="repository:app:pull,push",service="registry"`,
+			"bearer",
+			map[string]string{"realm": "https://auth.example.com/token", "scope": "repository:app:pull,push", "service": "registry"},
+		},
+		{
+			"unquoted params and spaces",
+			`BEARER Realm=https://auth.example.com/token, service=registry , error=invalid_token`,
+			"bearer",
+			map[string]string{"realm": "https://auth.example.com/token", "service": "registry", "error": "invalid_token"},
+		},
+		{"unterminated quote", `Bearer realm="https://auth.example.com/token`, "bearer", map[string]string{"realm": "https://auth.example.com/token"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := parseAuthChallenge(tt.header)
+			if ch.scheme != tt.wantScheme {
+				t.Errorf("scheme %q, want %q", ch.scheme, tt.wantScheme)
+			}
+			if len(ch.params) != len(tt.wantParams) {
+				t.Errorf("params %v, want %v", ch.params, tt.wantParams)
+			}
+			for k, v := range tt.wantParams {
+				if ch.params[k] != v {
+					t.Errorf("param %s %q, want %q", k, ch.params[k], v)
+				}
+			}
+		})
+	}
+}
+
+// authRepository is a repository requiring auth negotiated from WWW-Authenticate challenge.
+type authRepository struct {
+	srv *httptest.Server
+	// challenge returns WWW-Authenticate header of unauthorized responses.
+	challenge func() string
+	// authorized checks the request auth.
+	authorized func(r *http.Request) bool
+
+	fileRequests atomic.Int32
+}
+
+func newAuthRepository(t *testing.T, challenge func() string, authorized func(r *http.Request) bool) *authRepository {
+	t.Helper()
+	repo := &authRepository{challenge: challenge, authorized: authorized}
+	repo.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repo.fileRequests.Add(1)
+		if !repo.authorized(r) {
+			w.Header().Set("WWW-Authenticate", repo.challenge())
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("binary"))
+	}))
+	t.Cleanup(repo.srv.Close)
+	return repo
+}
+
+// newTokenService returns a token service issuing the token for user:pass credentials.
+func newTokenService(t *testing.T, token string, auth *atomic.Value) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.Store(r.Header.Get("Authorization"))
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"token":"` + token + `"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNegotiateAuth(t *testing.T) {
+	basicAuthorized := func(r *http.Request) bool {
+		user, pass, ok := r.BasicAuth()
+		return ok && user == "user" && pass == "pass"
+	}
+	bearerAuthorized := func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer registry-token"
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		// realm returns the token realm, an empty one means Basic challenge.
+		realm         func(tokenSrv *httptest.Server) string
+		authorized    func(r *http.Request) bool
+		wantErr       bool
+		wantTokenAuth bool
+		// wantRequests is 2 if the request is repeated with negotiated auth.
+		wantRequests int32
+	}{
+		{"basic", "pass", nil, basicAuthorized, false, false, 2},
+		{"basic invalid credentials", "wrong", nil, basicAuthorized, true, false, 2},
+		{"bearer realm", "pass", func(s *httptest.Server) string { return s.URL + "/token" }, bearerAuthorized, false, true, 2},
+		{"bearer invalid credentials", "wrong", func(s *httptest.Server) string { return s.URL + "/token" }, bearerAuthorized, true, true, 1},
+		{
+			"bearer realm of another site",
+			"pass",
+			func(s *httptest.Server) string { return strings.Replace(s.URL, "127.0.0.1", "localhost", 1) + "/token" },
+			bearerAuthorized,
+			true,
+			false,
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tokenAuth atomic.Value
+			tokenAuth.Store("")
+			tokenSrv := newTokenService(t, "registry-token", &tokenAuth)
+			challenge := func() string { return `Basic realm="repository"` }
+			if tt.realm != nil {
+				challenge = func() string {
+					return `Bearer realm="` + tt.realm(tokenSrv) + `",service="repository",scope="repository:app:pull,push"`
+				}
+			}
+			repo := newAuthRepository(t, challenge, tt.authorized)
+
+			u := &updateAction{
+				cfg:            &config{RepositoryURL: repo.srv.URL},
+				credentials:    keyring.CredentialsItem{Username: "user", Password: tt.password},
+				client:         repo.srv.Client(),
+				nonInteractive: true,
+			}
+			u.source = &httpSource{u: u}
+			if err := u.source.init(); err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := u.sendRequest(repo.srv.URL + "/app")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil {
+				resp.Body.Close()
+			}
+			// The request is repeated at most once, failed token exchange isn't followed by a request.
+			if n := repo.fileRequests.Load(); n != tt.wantRequests {
+				t.Errorf("%d file requests, want %d", n, tt.wantRequests)
+			}
+			if got := tokenAuth.Load().(string) != ""; got != tt.wantTokenAuth {
+				t.Errorf("credentials sent to token service %t, want %t", got, tt.wantTokenAuth)
+			}
+		})
+	}
+}
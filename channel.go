@@ -0,0 +1,102 @@
+package plasmactlupdate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultChannel is a channel using the default pinned release file.
+const defaultChannel = "stable"
+
+// channelReleaseTpl returns the pinned release template of the channel.
+func (cfg *config) channelReleaseTpl(channel string) (string, error) {
+	if tpl, ok := cfg.Channels[channel]; ok {
+		return tpl, nil
+	}
+	if channel == defaultChannel {
+		return cfg.PinnedRelease, nil
+	}
+
+	names := make([]string, 0, len(cfg.Channels)+1)
+	names = append(names, defaultChannel)
+	for name := range cfg.Channels {
+		if name != defaultChannel {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return "", fmt.Errorf("unknown release channel %q, available channels are [%s]", channel, strings.Join(names, ", "))
+}
+
+// resolveChannel sets the pinned release template from the requested or remembered channel.
+// For OCI source the channel is used as a tag to resolve.
+// GitHub and GitLab sources resolve the latest release themselves and can't follow a channel.
+// An explicitly requested channel is remembered after a successful update.
+func (u *updateAction) resolveChannel() error {
+	st, err := u.loadState()
+	if err != nil {
+		u.Log().Debug("failed to load user state", "error", err)
+	}
+
+	channel := u.channel
+	explicit := channel != ""
+	if !explicit {
+		// Explicit release file mask has priority over the remembered channel.
+		if u.pinnedOverride {
+			return nil
+		}
+		channel = st.Channel
+	}
+	if channel == "" {
+		return nil
+	}
+
+	if u.cfg.Source == sourceGitHub || u.cfg.Source == sourceGitLab {
+		if explicit {
+			return fmt.Errorf("release channels are not supported by %s source", u.cfg.Source)
+		}
+		u.Log().Debug("remembered release channel is ignored", "channel", channel, "source", u.cfg.Source)
+		return nil
+	}
+
+	if u.cfg.Source != sourceOCI {
+		tpl, err := u.cfg.channelReleaseTpl(channel)
+		if err != nil {
+			if explicit {
+				return err
+			}
+			u.Term().Warning().Printfln("Remembered release channel %q is not available, using %s", channel, defaultChannel)
+			return nil
+		}
+		u.cfg.PinnedRelease = tpl
+		u.Log().Debug("release channel", "channel", channel, "pinned_release_file", tpl)
+	}
+
+	u.activeChannel = channel
+	u.Term().Printfln("Using %s release channel", channel)
+
+	return nil
+}
+
+// rememberChannel saves the explicitly requested channel for the following runs.
+// It's called after a successful update, so a failed one doesn't switch the channel.
+func (u *updateAction) rememberChannel() {
+	if u.channel == "" || u.activeChannel == "" || u.checkOnly {
+		return
+	}
+
+	st, err := u.loadState()
+	if err != nil {
+		u.Log().Debug("failed to load user state", "error", err)
+	}
+	if st.Channel == u.activeChannel {
+		return
+	}
+
+	st.Channel = u.activeChannel
+	if err = u.saveState(st); err != nil {
+		u.Log().Error("error saving user state", "error", err)
+	}
+}
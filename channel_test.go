@@ -0,0 +1,105 @@
+package plasmactlupdate
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/launchrctl/launchr"
+)
+
+func TestResolveChannel(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		channel string
+		wantTpl string
+		wantErr bool
+	}{
+		{"http channel", sourceHTTP, "beta", "{{.URL}}/beta_release", false},
+		{"http stable", sourceHTTP, "stable", defaultPinnedReleaseTpl, false},
+		{"http unknown channel", sourceHTTP, "nightly", "", true},
+		{"oci channel is a tag", sourceOCI, "nightly", defaultPinnedReleaseTpl, false},
+		{"github rejects channel", sourceGitHub, "beta", "", true},
+		{"gitlab rejects channel", sourceGitLab, "beta", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			t.Setenv("HOME", t.TempDir())
+
+			u := &updateAction{
+				appName:   "app",
+				channel:   tt.channel,
+				checkOnly: true,
+				cfg: &config{
+					Source:        tt.source,
+					PinnedRelease: defaultPinnedReleaseTpl,
+					Channels:      map[string]string{"beta": "{{.URL}}/beta_release"},
+				},
+			}
+			err := u.resolveChannel()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if u.cfg.PinnedRelease != tt.wantTpl {
+				t.Fatalf("pinned release %q, want %q", u.cfg.PinnedRelease, tt.wantTpl)
+			}
+			if u.activeChannel != tt.channel {
+				t.Fatalf("active channel %q, want %q", u.activeChannel, tt.channel)
+			}
+		})
+	}
+}
+
+func TestChannelRememberedAfterSuccess(t *testing.T) {
+	// The beta binary is missing, so the update to it fails.
+	srv := httptest.NewServer(serveFiles(map[string]string{
+		"/stable_release": "1.2.0",
+		"/beta_release":   "1.3.0-beta.1",
+	}))
+	defer srv.Close()
+
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	run := func(current string) error {
+		u := &updateAction{
+			cfg: &config{
+				RepositoryURL: srv.URL,
+				PinnedRelease: "{{.URL}}/stable_release",
+				BinMask:       "{{.URL}}/{{.Version}}/{{.Name}}",
+				Channels:      map[string]string{"beta": "{{.URL}}/beta_release"},
+			},
+			channel:        "beta",
+			nonInteractive: true,
+		}
+		defer u.cleanup()
+		return u.run(&launchr.AppVersion{Name: "app", Version: current})
+	}
+	remembered := func() string {
+		u := &updateAction{appName: "app"}
+		st, err := u.loadState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return st.Channel
+	}
+
+	if err := run("1.2.0"); err == nil {
+		t.Fatal("update must fail without the binary")
+	}
+	if ch := remembered(); ch != "" {
+		t.Fatalf("channel %q is remembered after failed update", ch)
+	}
+
+	if err := run("1.3.0-beta.1"); err != nil {
+		t.Fatal(err)
+	}
+	if ch := remembered(); ch != "beta" {
+		t.Fatalf("remembered channel %q, want beta", ch)
+	}
+}
@@ -0,0 +1,104 @@
+package plasmactlupdate
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// verifyChecksum downloads the checksum file for the given version and compares it with the downloaded binary.
+// The digest is looked up in the checksum file by the binary file name.
+func (u *updateAction) verifyChecksum(version, binName string) error {
+	checksumURL, err := u.releaseFileURL(u.cfg.ChecksumMask, version)
+	if err != nil {
+		return fmt.Errorf("failed to format checksum URL: %w", err)
+	}
+
+	u.Term().Printfln("Verifying checksum: %s", checksumURL)
+	resp, err := u.sendRequest(checksumURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	expected, err := parseChecksumFile(resp.Body, binName)
+	if err != nil {
+		return err
+	}
+
+	actual, err := fileSHA256(u.fDownloadPath)
+	if err != nil {
+		return err
+	}
+
+	u.Log().Debug("checksum verification", "expected", expected, "actual", actual)
+	if !strings.EqualFold(expected, actual) {
+		return fmt.Errorf("%w: expected %s, got %s", errChecksumMismatch, expected, actual)
+	}
+
+	return nil
+}
+
+// parseChecksumFile finds a SHA-256 digest for the file name.
+// Both SHA256SUMS style files ("<digest>  <name>" per line) and
+// single digest files (e.g. "<name>.sha256") are supported.
+func parseChecksumFile(r io.Reader, name string) (string, error) {
+	scanner := bufio.NewScanner(r)
+	var single []string
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) == 1 {
+			single = append(single, fields[0])
+			continue
+		}
+		// Binary mode entries are prefixed with '*'.
+		fname := strings.TrimPrefix(fields[1], "*")
+		if fname == name || filepath.Base(fname) == name {
+			return validateDigest(fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read checksum file: %w", err)
+	}
+
+	if len(single) == 1 {
+		return validateDigest(single[0])
+	}
+
+	return "", fmt.Errorf("checksum for %s not found", name)
+}
+
+func validateDigest(digest string) (string, error) {
+	b, err := hex.DecodeString(digest)
+	if err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("invalid SHA-256 digest %q", digest)
+	}
+	return strings.ToLower(digest), nil
+}
+
+// fileSHA256 returns a hex encoded SHA-256 digest of the file.
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(filepath.Clean(p))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
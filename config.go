@@ -6,8 +6,11 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/launchrctl/launchr"
 	"gopkg.in/yaml.v3"
@@ -19,9 +22,106 @@ const (
 )
 
 type config struct {
-	RepositoryURL string `yaml:"repository_url"`
-	PinnedRelease string `yaml:"pinned_release_file"`
-	BinMask       string `yaml:"bin_mask"`
+	ProxyURL            string            `yaml:"proxy_url"`
+	NoProxy             string            `yaml:"no_proxy"`
+	TLS                 tlsConfig         `yaml:"tls"`
+	Auth                authConfig        `yaml:"auth"`
+	RepositoryURLs      []mirror          `yaml:"repository_urls"`
+	Source              string            `yaml:"source"`
+	GitHub              githubConfig      `yaml:"github"`
+	GitLab              gitlabConfig      `yaml:"gitlab"`
+	OCI                 ociConfig         `yaml:"oci"`
+	S3                  s3Config          `yaml:"s3"`
+	RepositoryURL       string            `yaml:"repository_url"`
+	PinnedRelease       string            `yaml:"pinned_release_file"`
+	BinMask             string            `yaml:"bin_mask"`
+	ChecksumMask        string            `yaml:"checksum_mask"`
+	SignatureMask       string            `yaml:"signature_mask"`
+	PublicKeys          []string          `yaml:"public_keys"`
+	SmokeCommand        string            `yaml:"smoke_command"`
+	SmokeTimeout        time.Duration     `yaml:"smoke_timeout"`
+	Channels            map[string]string `yaml:"channels"`
+	ReleaseFormat       string            `yaml:"release_format"`
+	ConnectTimeout      time.Duration     `yaml:"connect_timeout"`
+	Timeout             time.Duration     `yaml:"timeout"`
+	Retries             int               `yaml:"retries"`
+	ArchiveFormat       string            `yaml:"archive_format"`
+	BinaryPathInArchive string            `yaml:"binary_path_in_archive"`
+	InstallMode         string            `yaml:"install_mode"`
+	InstallLayout       string            `yaml:"install_layout"`
+	KeepVersions        int               `yaml:"keep_versions"`
+	PrivilegeEscalation string            `yaml:"privilege_escalation"`
+}
+
+// mirror is a repository URL with an optional weight.
+// Mirrors with a higher weight are tried first.
+type mirror struct {
+	URL    string `yaml:"url"`
+	Weight int    `yaml:"weight"`
+}
+
+// UnmarshalYAML implements [yaml.Unmarshaler] to support plain string URLs.
+func (m *mirror) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		m.URL = value.Value
+		return nil
+	}
+
+	type plain mirror
+	return value.Decode((*plain)(m))
+}
+
+// mirrorURLs returns repository URLs in order they must be tried.
+func (cfg *config) mirrorURLs() []string {
+	if len(cfg.RepositoryURLs) == 0 {
+		return []string{cfg.RepositoryURL}
+	}
+
+	mirrors := make([]mirror, len(cfg.RepositoryURLs))
+	copy(mirrors, cfg.RepositoryURLs)
+	sort.SliceStable(mirrors, func(i, j int) bool {
+		return mirrors[i].Weight > mirrors[j].Weight
+	})
+
+	urls := make([]string, 0, len(mirrors))
+	for _, m := range mirrors {
+		urls = append(urls, m.URL)
+	}
+	return urls
+}
+
+// githubConfig is a configuration of GitHub Releases source.
+type githubConfig struct {
+	Owner     string `yaml:"owner"`
+	Repo      string `yaml:"repo"`
+	AssetMask string `yaml:"asset_mask"`
+	APIURL    string `yaml:"api_url"`
+}
+
+// gitlabConfig is a configuration of GitLab generic package registry source.
+type gitlabConfig struct {
+	URL          string `yaml:"url"`
+	Project      string `yaml:"project"`
+	PackageName  string `yaml:"package_name"`
+	FileMask     string `yaml:"file_mask"`
+	TokenType    string `yaml:"token_type"`
+	VersionsFrom string `yaml:"versions_from"`
+}
+
+// ociConfig is a configuration of OCI registry source.
+type ociConfig struct {
+	Registry   string `yaml:"registry"`
+	Repository string `yaml:"repository"`
+	Tag        string `yaml:"tag"`
+	FileMask   string `yaml:"file_mask"`
+}
+
+// s3Config is a configuration of S3-compatible object storage source.
+type s3Config struct {
+	Bucket   string `yaml:"bucket"`
+	Prefix   string `yaml:"prefix"`
+	Region   string `yaml:"region"`
+	Endpoint string `yaml:"endpoint"`
 }
 
 // Global variable for update config
@@ -80,10 +180,117 @@ func parseConfigFromBytes(data []byte) (*config, error) {
 
 // validateConfig checks if all fields are filled and not empty
 func validateConfig(cfg *config) error {
-	if cfg.RepositoryURL == "" {
-		return fmt.Errorf("field 'repository_url' is required and cannot be empty")
+	switch cfg.Source {
+	case "", sourceHTTP:
+		if cfg.RepositoryURL == "" && len(cfg.RepositoryURLs) == 0 {
+			return fmt.Errorf("field 'repository_url' is required and cannot be empty")
+		}
+		for i, m := range cfg.RepositoryURLs {
+			if err := validateURL(m.URL); err != nil {
+				return fmt.Errorf("field 'repository_urls[%d]' is invalid: %w", i, err)
+			}
+		}
+	case sourceGitHub:
+		if cfg.GitHub.Owner == "" || cfg.GitHub.Repo == "" {
+			return fmt.Errorf("fields 'github.owner' and 'github.repo' are required for %s source", sourceGitHub)
+		}
+		if cfg.GitHub.APIURL != "" {
+			if err := validateURL(cfg.GitHub.APIURL); err != nil {
+				return fmt.Errorf("field 'github.api_url' is invalid: %w", err)
+			}
+		}
+	case sourceGitLab:
+		if err := validateGitLabConfig(cfg.GitLab); err != nil {
+			return err
+		}
+	case sourceOCI:
+		if cfg.OCI.Registry == "" || cfg.OCI.Repository == "" {
+			return fmt.Errorf("fields 'oci.registry' and 'oci.repository' are required for %s source", sourceOCI)
+		}
+		if err := validateURL(cfg.OCI.Registry); err != nil {
+			return fmt.Errorf("field 'oci.registry' is invalid: %w", err)
+		}
+	case sourceS3:
+		if cfg.S3.Bucket == "" || cfg.S3.Region == "" {
+			return fmt.Errorf("fields 's3.bucket' and 's3.region' are required for %s source", sourceS3)
+		}
+		if cfg.S3.Endpoint != "" {
+			if err := validateURL(cfg.S3.Endpoint); err != nil {
+				return fmt.Errorf("field 's3.endpoint' is invalid: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("field 'source' has unsupported value %q", cfg.Source)
+	}
+
+	if err := validateAuthConfig(cfg.Auth); err != nil {
+		return err
+	}
+	if err := validateTLSConfig(cfg.TLS); err != nil {
+		return err
+	}
+	if cfg.ProxyURL != "" {
+		if _, err := parseProxyURL(cfg.ProxyURL); err != nil {
+			return fmt.Errorf("field 'proxy_url' is invalid: %w", err)
+		}
+	}
+
+	if cfg.ReleaseFormat != "" && cfg.ReleaseFormat != releaseFormatText && cfg.ReleaseFormat != releaseFormatManifest {
+		return fmt.Errorf("field 'release_format' must be one of [%s, %s]", releaseFormatText, releaseFormatManifest)
+	}
+	if cfg.ReleaseFormat == releaseFormatManifest && cfg.Source != "" && cfg.Source != sourceHTTP && cfg.Source != sourceS3 {
+		return fmt.Errorf("field 'release_format' %s is supported only by %s and %s sources", releaseFormatManifest, sourceHTTP, sourceS3)
 	}
 
+	if cfg.ArchiveFormat != "" && cfg.ArchiveFormat != archiveFormatTarGz && cfg.ArchiveFormat != archiveFormatZip {
+		return fmt.Errorf("field 'archive_format' must be one of [%s, %s]", archiveFormatTarGz, archiveFormatZip)
+	}
+
+	if cfg.InstallMode != "" && cfg.InstallMode != installModeSystem && cfg.InstallMode != installModeUser {
+		return fmt.Errorf("field 'install_mode' must be one of [%s, %s]", installModeSystem, installModeUser)
+	}
+	if cfg.InstallLayout != "" && cfg.InstallLayout != installLayoutInPlace && cfg.InstallLayout != installLayoutVersioned {
+		return fmt.Errorf("field 'install_layout' must be one of [%s, %s]", installLayoutInPlace, installLayoutVersioned)
+	}
+	if cfg.KeepVersions < 0 {
+		return fmt.Errorf("field 'keep_versions' can't be negative")
+	}
+	if cfg.PrivilegeEscalation != "" && cfg.PrivilegeEscalation != escalationAuto && cfg.PrivilegeEscalation != escalationNone &&
+		!slices.Contains(escalationCmds, cfg.PrivilegeEscalation) {
+		return fmt.Errorf("field 'privilege_escalation' must be one of %v", append([]string{escalationAuto, escalationNone}, escalationCmds...))
+	}
+
+	for name, tpl := range cfg.Channels {
+		if name == "" || tpl == "" {
+			return fmt.Errorf("channel %q must have a non-empty name and pinned release template", name)
+		}
+	}
+
+	if len(cfg.PublicKeys) > 0 {
+		if cfg.SignatureMask == "" {
+			return fmt.Errorf("field 'signature_mask' is required when 'public_keys' are set")
+		}
+		if _, err := parsePublicKeys(cfg.PublicKeys); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateGitLabConfig(cfg gitlabConfig) error {
+	if cfg.URL == "" || cfg.Project == "" {
+		return fmt.Errorf("fields 'gitlab.url' and 'gitlab.project' are required for %s source", sourceGitLab)
+	}
+	if err := validateURL(cfg.URL); err != nil {
+		return fmt.Errorf("field 'gitlab.url' is invalid: %w", err)
+	}
+	if cfg.TokenType != "" && cfg.TokenType != gitlabTokenPrivate && cfg.TokenType != gitlabTokenJob {
+		return fmt.Errorf("field 'gitlab.token_type' must be one of [%s, %s]", gitlabTokenPrivate, gitlabTokenJob)
+	}
+	if cfg.VersionsFrom != "" && cfg.VersionsFrom != gitlabVersionsReleases && cfg.VersionsFrom != gitlabVersionsPackages {
+		return fmt.Errorf("field 'gitlab.versions_from' must be one of [%s, %s]", gitlabVersionsReleases, gitlabVersionsPackages)
+	}
 	return nil
 }
 
@@ -96,9 +303,9 @@ type templateVars struct {
 	Ext     string
 }
 
-// formatURL formats a template string with the provided variables
-func formatURL(templateStr string, vars templateVars) (string, error) {
-	tmpl, err := template.New("url").Parse(templateStr)
+// formatTemplate formats a template string with the provided variables
+func formatTemplate(templateStr string, vars templateVars) (string, error) {
+	tmpl, err := template.New("tpl").Parse(templateStr)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
@@ -109,7 +316,15 @@ func formatURL(templateStr string, vars templateVars) (string, error) {
 		return "", fmt.Errorf("failed to execute template: %w", err)
 	}
 
-	result := strings.TrimSpace(buf.String())
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// formatURL formats a template string with the provided variables
+func formatURL(templateStr string, vars templateVars) (string, error) {
+	result, err := formatTemplate(templateStr, vars)
+	if err != nil {
+		return "", err
+	}
 
 	// Validate the resulting URL
 	if err = validateURL(result); err != nil {
@@ -0,0 +1,255 @@
+package plasmactlupdate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultTimeout        = 30 * time.Minute
+	defaultRetries        = 3
+
+	maxRedirects = 10
+
+	retryBaseDelay    = time.Second
+	retryMaxDelay     = 30 * time.Second
+	progressInterval  = 200 * time.Millisecond
+	progressUnknownMB = 1 << 20
+)
+
+// permanentError is an error which must not be retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// retryableError is a server error which may succeed on the next attempt.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// isTransient checks the request may succeed if repeated, e.g. after a timeout,
+// a dropped connection or a server error. TLS verification, DNS resolution,
+// proxy configuration and local I/O errors are permanent.
+func isTransient(err error) bool {
+	var perm *permanentError
+	if errors.As(err, &perm) {
+		return false
+	}
+	var re *retryableError
+	if errors.As(err, &re) {
+		return true
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	// The connection dropped while reading the response.
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "read"
+}
+
+// newHTTPClient creates an HTTP client with timeouts and TLS settings from the config.
+// The client is shared by all requests of the update.
+func (u *updateAction) newHTTPClient() (*http.Client, error) {
+	cfg := u.cfg
+	connectTimeout := cfg.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+
+	tc, err := u.newTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tc
+
+	if transport.Proxy, err = u.proxyFunc(); err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport:     transport,
+		Timeout:       timeout,
+		CheckRedirect: u.checkRedirect,
+	}, nil
+}
+
+// checkRedirect drops auth headers when the request is redirected to another host,
+// e.g. from a package registry to presigned object storage URL.
+// Unlike Authorization, custom headers are forwarded by the client as is.
+func (u *updateAction) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if req.URL.Host == via[0].URL.Host {
+		return nil
+	}
+
+	u.Log().Debug("redirected to another host, dropping auth headers", "url", req.URL.Redacted())
+	for _, h := range u.authHeaders() {
+		req.Header.Del(h)
+	}
+	return nil
+}
+
+// authHeaders returns names of headers carrying credentials of any source.
+func (u *updateAction) authHeaders() []string {
+	headers := []string{"Authorization", gitlabHeaderPrivate, gitlabHeaderJob}
+	if u.cfg.Auth.Header != "" {
+		headers = append(headers, u.cfg.Auth.Header)
+	}
+	return headers
+}
+
+// retry calls fn until it succeeds, returns a not transient error or retries are exhausted.
+// Delay between attempts grows exponentially.
+func (u *updateAction) retry(fn func() error) error {
+	retries := u.cfg.Retries
+	if retries <= 0 {
+		retries = defaultRetries
+	}
+
+	delay := retryBaseDelay
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+
+		if !isTransient(err) {
+			var perm *permanentError
+			if errors.As(err, &perm) {
+				return perm.err
+			}
+			return err
+		}
+		if attempt >= retries {
+			return err
+		}
+
+		u.Log().Debug("request failed, retrying", "attempt", attempt+1, "delay", delay, "error", err)
+		u.Term().Warning().Printfln("Request failed: %s. Retrying in %s...", err, delay)
+		time.Sleep(delay)
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// downloadToFile downloads the URL into dst, resuming with HTTP Range requests after dropped connections.
+func (u *updateAction) downloadToFile(fileURL, dst string) error {
+	out, err := os.OpenFile(filepath.Clean(dst), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	progress := &progressWriter{u: u}
+	defer progress.finish()
+
+	return u.retry(func() error {
+		offset, err := out.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return &permanentError{err}
+		}
+
+		resp, err := u.sendRangeRequest(fileURL, offset)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+			// The server doesn't support ranges, start over.
+			u.Log().Debug("server ignored range request, restarting download", "url", fileURL)
+			if err = out.Truncate(0); err != nil {
+				return &permanentError{err}
+			}
+			if _, err = out.Seek(0, io.SeekStart); err != nil {
+				return &permanentError{err}
+			}
+			offset = 0
+		}
+
+		progress.start(offset, resp.ContentLength)
+		_, err = io.Copy(io.MultiWriter(out, progress), resp.Body)
+		return err
+	})
+}
+
+// progressWriter reports download progress to the terminal.
+type progressWriter struct {
+	u       *updateAction
+	total   int64
+	written int64
+	last    time.Time
+	printed bool
+}
+
+func (p *progressWriter) start(offset, contentLength int64) {
+	p.written = offset
+	p.total = -1
+	if contentLength >= 0 {
+		p.total = offset + contentLength
+	}
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if time.Since(p.last) >= progressInterval {
+		p.print()
+	}
+	return len(b), nil
+}
+
+func (p *progressWriter) print() {
+	p.last = time.Now()
+	p.printed = true
+	if p.total > 0 {
+		p.u.Term().Printf("\rDownloaded %5.1f%% (%s / %s)", float64(p.written)*100/float64(p.total), formatBytes(p.written), formatBytes(p.total))
+		return
+	}
+	p.u.Term().Printf("\rDownloaded %s", formatBytes(p.written))
+}
+
+func (p *progressWriter) finish() {
+	if p.printed {
+		p.print()
+		p.u.Term().Println()
+	}
+}
+
+// formatBytes returns a human-readable size.
+func formatBytes(n int64) string {
+	if n < progressUnknownMB {
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/progressUnknownMB)
+}
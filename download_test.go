@@ -0,0 +1,89 @@
+package plasmactlupdate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsTransient(t *testing.T) {
+	// Untrusted certificate of the TLS server.
+	tlsSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsSrv.Close()
+	_, errTLS := http.Get(tlsSrv.URL) //nolint:noctx // Test request.
+
+	// Timeout of the slow server.
+	slow := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+	_, errTimeout := (&http.Client{Timeout: 50 * time.Millisecond}).Get(slow.URL) //nolint:noctx // Test request.
+
+	// Response body shorter than announced.
+	truncated := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		_, _ = w.Write([]byte("partial"))
+	}))
+	defer truncated.Close()
+	resp, err := http.Get(truncated.URL) //nolint:noctx // Test request.
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errBody := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	errWrite := os.WriteFile(filepath.Join(t.TempDir(), "missing", "app"), nil, 0600)
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &retryableError{errors.New("HTTP 503")}, true},
+		{"client error", &permanentError{errors.New("HTTP 404")}, false},
+		{"timeout", errTimeout, true},
+		{"truncated body", errBody, true},
+		{"connection reset", &net.OpError{Op: "write", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		{"dropped connection", &net.OpError{Op: "read", Net: "tcp", Err: errors.New("use of closed network connection")}, true},
+		{"certificate", errTLS, false},
+		{"unknown host", fmt.Errorf("get: %w", &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "repo.invalid", IsNotFound: true}}), false},
+		{"invalid proxy", &net.OpError{Op: "proxyconnect", Net: "tcp", Err: errors.New("connection refused")}, false},
+		{"local write", errWrite, false},
+		{"permanent timeout", &permanentError{errTimeout}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil {
+				t.Fatal("test error is not produced")
+			}
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnPermanentError(t *testing.T) {
+	u := &updateAction{cfg: &config{Retries: 3}}
+
+	attempts := 0
+	errTLS := errors.New("x509: certificate signed by unknown authority")
+	err := u.retry(func() error {
+		attempts++
+		return errTLS
+	})
+	if !errors.Is(err, errTLS) || attempts != 1 {
+		t.Fatalf("error %v after %d attempts, want a single attempt", err, attempts)
+	}
+}
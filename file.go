@@ -0,0 +1,88 @@
+package plasmactlupdate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// oldBinarySuffix is appended to the running binary moved aside during the update.
+const oldBinarySuffix = ".old"
+
+// fileSystem is a subset of filesystem operations used to replace the binary.
+type fileSystem interface {
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	Stat(name string) (fs.FileInfo, error)
+}
+
+// osFS implements [fileSystem] with the os package.
+type osFS struct{}
+
+func (osFS) Rename(oldpath, newpath string) error  { return os.Rename(oldpath, newpath) }
+func (osFS) Remove(name string) error              { return os.Remove(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+
+// hasWritePermissions checks the directory is writable by creating a temp file in it.
+// Unlike checking mode bits, it respects root, ACLs and read-only mounts.
+func hasWritePermissions(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("error checking binary directory permissions: %w", err)
+	}
+
+	f, err := os.CreateTemp(path, ".write-probe-*")
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return errNoWritePermission
+		}
+		return fmt.Errorf("error checking binary directory permissions: %w", err)
+	}
+
+	name := f.Name()
+	_ = f.Close()
+	return os.Remove(name)
+}
+
+// replaceRunningBinary renames src over dst when dst may be a running executable.
+// A running executable can't be replaced on windows but can be renamed,
+// so dst is moved aside to dst.old, which is removed on the next start.
+func replaceRunningBinary(fsys fileSystem, src, dst string) error {
+	// The binary isn't running, e.g. when restoring a backup after the update.
+	if err := fsys.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	old := dst + oldBinarySuffix
+	if err := fsys.Remove(old); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove binary %s left by the previous update: %w", old, err)
+	}
+
+	moved := false
+	if _, err := fsys.Stat(dst); err == nil {
+		if err = fsys.Rename(dst, old); err != nil {
+			return fmt.Errorf("failed to move running binary %s aside: %w", dst, err)
+		}
+		moved = true
+	}
+
+	if err := fsys.Rename(src, dst); err != nil {
+		if moved {
+			if errRestore := fsys.Rename(old, dst); errRestore != nil {
+				return fmt.Errorf("%w, restoring %s failed: %w", err, dst, errRestore)
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// cleanupOldBinary removes the binary moved aside by the previous update.
+func cleanupOldBinary(fsys fileSystem, path string) error {
+	err := fsys.Remove(path + oldBinarySuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
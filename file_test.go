@@ -0,0 +1,218 @@
+package plasmactlupdate
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+// fakeFS is an in-memory [fileSystem] behaving like windows for running executables:
+// they can be renamed, but can't be removed or replaced.
+type fakeFS struct {
+	files   map[string]string
+	running map[string]bool
+	// fail makes renames of the path fail.
+	fail map[string]error
+}
+
+func newFakeFS(files map[string]string, running ...string) *fakeFS {
+	f := &fakeFS{files: files, running: make(map[string]bool), fail: make(map[string]error)}
+	for _, name := range running {
+		f.running[name] = true
+	}
+	return f
+}
+
+func (f *fakeFS) Rename(oldpath, newpath string) error {
+	if err := f.fail[oldpath]; err != nil {
+		return &fs.PathError{Op: "rename", Path: oldpath, Err: err}
+	}
+	data, ok := f.files[oldpath]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: oldpath, Err: fs.ErrNotExist}
+	}
+	if f.running[newpath] {
+		return &fs.PathError{Op: "rename", Path: newpath, Err: fs.ErrPermission}
+	}
+
+	f.files[newpath] = data
+	f.running[newpath] = f.running[oldpath]
+	delete(f.files, oldpath)
+	delete(f.running, oldpath)
+	return nil
+}
+
+func (f *fakeFS) Remove(name string) error {
+	if _, ok := f.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if f.running[name] {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+	}
+	delete(f.files, name)
+	return nil
+}
+
+func (f *fakeFS) Stat(name string) (fs.FileInfo, error) {
+	if _, ok := f.files[name]; !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	// Only the error is checked.
+	return nil, nil
+}
+
+func (f *fakeFS) assertFiles(t *testing.T, want map[string]string) {
+	t.Helper()
+	if len(f.files) != len(want) {
+		t.Errorf("files %v, want %v", f.files, want)
+		return
+	}
+	for name, data := range want {
+		if got, ok := f.files[name]; !ok || got != data {
+			t.Errorf("file %s is %q, want %q", name, got, data)
+		}
+	}
+}
+
+func TestReplaceRunningBinary(t *testing.T) {
+	const (
+		src = "app.exe.tmp"
+		dst = "app.exe"
+		old = "app.exe.old"
+	)
+	errDisk := errors.New("disk failure")
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		running []string
+		fail    map[string]error
+		wantErr bool
+		want    map[string]string
+	}{
+		{
+			name:  "not running",
+			files: map[string]string{src: "new", dst: "current"},
+			want:  map[string]string{dst: "new"},
+		},
+		{
+			name:    "running is moved aside",
+			files:   map[string]string{src: "new", dst: "current"},
+			running: []string{dst},
+			want:    map[string]string{dst: "new", old: "current"},
+		},
+		{
+			name:    "leftover old binary is removed",
+			files:   map[string]string{src: "new", dst: "current", old: "previous"},
+			running: []string{dst},
+			want:    map[string]string{dst: "new", old: "current"},
+		},
+		{
+			name:    "leftover old binary can't be removed",
+			files:   map[string]string{src: "new", dst: "current", old: "previous"},
+			running: []string{dst, old},
+			wantErr: true,
+			want:    map[string]string{src: "new", dst: "current", old: "previous"},
+		},
+		{
+			name:    "running can't be moved aside",
+			files:   map[string]string{src: "new", dst: "current"},
+			running: []string{dst},
+			fail:    map[string]error{dst: errDisk},
+			wantErr: true,
+			want:    map[string]string{src: "new", dst: "current"},
+		},
+		{
+			name:    "running is restored when new binary can't be renamed",
+			files:   map[string]string{src: "new", dst: "current"},
+			running: []string{dst},
+			fail:    map[string]error{src: errDisk},
+			wantErr: true,
+			want:    map[string]string{src: "new", dst: "current"},
+		},
+		{
+			name:    "missing destination isn't restored",
+			files:   map[string]string{src: "new"},
+			fail:    map[string]error{src: errDisk},
+			wantErr: true,
+			want:    map[string]string{src: "new"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := newFakeFS(tt.files, tt.running...)
+			for name, err := range tt.fail {
+				fsys.fail[name] = err
+			}
+
+			err := replaceRunningBinary(fsys, src, dst)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			fsys.assertFiles(t, tt.want)
+		})
+	}
+}
+
+func TestReplaceRunningBinaryRestoreFailure(t *testing.T) {
+	errDisk := errors.New("disk failure")
+	fsys := newFakeFS(map[string]string{"app.exe.tmp": "new", "app.exe": "current"}, "app.exe")
+	fsys.fail["app.exe.tmp"] = errDisk
+
+	// Fail restoring the running binary after it's moved aside.
+	err := replaceRunningBinary(&restoreFailFS{fsys}, "app.exe.tmp", "app.exe")
+	if !errors.Is(err, errDisk) || !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("error must wrap rename and restore failures: %v", err)
+	}
+	fsys.assertFiles(t, map[string]string{"app.exe.tmp": "new", "app.exe.old": "current"})
+}
+
+// restoreFailFS fails renames of the old binary back.
+type restoreFailFS struct {
+	*fakeFS
+}
+
+func (f *restoreFailFS) Rename(oldpath, newpath string) error {
+	if oldpath == "app.exe.old" {
+		return &fs.PathError{Op: "rename", Path: oldpath, Err: fs.ErrPermission}
+	}
+	return f.fakeFS.Rename(oldpath, newpath)
+}
+
+func TestCleanupOldBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		running []string
+		wantErr bool
+		want    map[string]string
+	}{
+		{
+			name:  "old binary is removed",
+			files: map[string]string{"app.exe": "current", "app.exe.old": "previous"},
+			want:  map[string]string{"app.exe": "current"},
+		},
+		{
+			name:  "no old binary",
+			files: map[string]string{"app.exe": "current"},
+			want:  map[string]string{"app.exe": "current"},
+		},
+		{
+			name:    "old binary is still running",
+			files:   map[string]string{"app.exe": "current", "app.exe.old": "previous"},
+			running: []string{"app.exe.old"},
+			wantErr: true,
+			want:    map[string]string{"app.exe": "current", "app.exe.old": "previous"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := newFakeFS(tt.files, tt.running...)
+			err := cleanupOldBinary(fsys, "app.exe")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			fsys.assertFiles(t, tt.want)
+		})
+	}
+}
@@ -3,43 +3,40 @@
 package plasmactlupdate
 
 import (
-	"fmt"
 	"os"
-	"os/user"
 	"syscall"
 )
 
-func hasWritePermissions(path string) error {
-	fileInfo, err := os.Stat(path)
-	if err != nil {
-		return fmt.Errorf("error checking binary directory permissions: %w", err)
-	}
+// replaceBinary renames src over dst, a running binary can be replaced in place on unix.
+func replaceBinary(src, dst string) error {
+	return os.Rename(src, dst)
+}
 
-	fileStat := fileInfo.Sys().(*syscall.Stat_t)
-	currentUID := os.Getuid()
-	fileOwnerUID := int(fileStat.Uid)
-	fileGroupGID := int(fileStat.Gid)
+// cleanupPreviousUpdate does nothing on unix, the running binary isn't moved aside.
+func cleanupPreviousUpdate() {}
 
-	currentUser, err := user.Current()
+// fileOwner returns uid and gid of the file owner.
+func fileOwner(path string) (uid, gid int, ok bool) {
+	fi, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("error getting current user: %w", err)
+		return 0, 0, false
 	}
-	groups, err := currentUser.GroupIds()
-	if err != nil {
-		return fmt.Errorf("error getting user groups: %w", err)
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
 	}
+	return int(st.Uid), int(st.Gid), true
+}
 
-	userGroups := make(map[string]bool)
-	for _, g := range groups {
-		userGroups[g] = true
+// preserveOwner sets the owner of dst on the file when running as root,
+// so the replaced binary isn't silently taken over by root.
+func preserveOwner(path, dst string) error {
+	if os.Geteuid() != 0 {
+		return nil
 	}
-
-	hasOwnerWrite := fileInfo.Mode().Perm()&(1<<(uint(7))) != 0
-	hasGroupWrite := fileInfo.Mode().Perm()&(1<<(uint(4))) != 0 && userGroups[fmt.Sprint(fileGroupGID)]
-
-	if (currentUID == fileOwnerUID && hasOwnerWrite) || hasGroupWrite {
+	uid, gid, ok := fileOwner(dst)
+	if !ok {
 		return nil
 	}
-
-	return errNoWritePermission
+	return os.Chown(path, uid, gid)
 }
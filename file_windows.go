@@ -2,8 +2,38 @@
 
 package plasmactlupdate
 
-func hasWritePermissions(path string) error {
-	//@todo handle windows update
+import (
+	"os"
+	"path/filepath"
 
-	return errNoWritePermission
+	"github.com/launchrctl/launchr"
+)
+
+// replaceBinary renames src over dst moving the running binary aside.
+func replaceBinary(src, dst string) error {
+	return replaceRunningBinary(osFS{}, src, dst)
+}
+
+// cleanupPreviousUpdate removes the binary moved aside by the previous update.
+func cleanupPreviousUpdate() {
+	execPath, err := os.Executable()
+	if err != nil {
+		return
+	}
+	if evalPath, err := filepath.EvalSymlinks(execPath); err == nil {
+		execPath = evalPath
+	}
+	if err = cleanupOldBinary(osFS{}, execPath); err != nil {
+		launchr.Log().Debug("error removing binary left by the previous update", "path", execPath+oldBinarySuffix, "error", err)
+	}
+}
+
+// fileOwner isn't supported on windows.
+func fileOwner(_ string) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
+
+// preserveOwner isn't supported on windows.
+func preserveOwner(_, _ string) error {
+	return nil
 }
@@ -105,11 +105,7 @@ func copyFile(src, dst string) error {
 }
 
 func createDefaultConfigFile(path string) {
-	defaultConfig := &config{
-		RepositoryURL: "",
-		PinnedRelease: "",
-		BinMask:       "",
-	}
+	defaultConfig := &config{}
 
 	err := launchr.EnsurePath(filepath.Dir(path))
 	if err != nil {
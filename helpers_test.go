@@ -0,0 +1,48 @@
+package plasmactlupdate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestAction returns an update action downloading from the test server with the config source.
+func newTestAction(t *testing.T, srv *httptest.Server, cfg *config) *updateAction {
+	t.Helper()
+	if cfg.Source == "" && cfg.RepositoryURL == "" && len(cfg.RepositoryURLs) == 0 {
+		cfg.RepositoryURL = srv.URL
+	}
+
+	// Tests never use the keyring.
+	u := &updateAction{
+		cfg:            cfg,
+		appName:        "app",
+		fName:          "app",
+		os:             "Linux",
+		arch:           "x86_64",
+		client:         srv.Client(),
+		nonInteractive: true,
+	}
+	src, err := u.newSource()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.source = src
+	if err = u.source.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+// serveFiles returns a handler serving files by the request path.
+func serveFiles(files map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(data))
+	}
+}
@@ -0,0 +1,290 @@
+package plasmactlupdate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/launchrctl/launchr"
+)
+
+const (
+	installModeSystem = "system"
+	installModeUser   = "user"
+
+	installLayoutInPlace   = "inplace"
+	installLayoutVersioned = "versioned"
+
+	versionsDirName     = "versions"
+	defaultKeepVersions = 3
+)
+
+// userBinDir returns a per-user directory for binaries, $XDG_BIN_HOME or ~/.local/bin.
+func userBinDir() (string, error) {
+	if dir := os.Getenv("XDG_BIN_HOME"); dir != "" {
+		return filepath.Abs(dir)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "bin"), nil
+}
+
+// expandHome replaces a leading ~ in the path with the user home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// resolveInstallDir returns a directory to install the binary to instead of the running one.
+// Empty string is returned if the running binary must be replaced in place.
+func (u *updateAction) resolveInstallDir() (string, error) {
+	if u.installDir != "" {
+		dir, err := expandHome(u.installDir)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Abs(dir)
+	}
+
+	if u.cfg.InstallMode == installModeUser {
+		return userBinDir()
+	}
+
+	return "", nil
+}
+
+// warnInstallDirPath warns when the install directory isn't on PATH
+// or when another copy of the binary found earlier on PATH shadows the installed one.
+func (u *updateAction) warnInstallDirPath() {
+	if !u.userInstall {
+		return
+	}
+
+	onPath := false
+	for _, p := range filepath.SplitList(os.Getenv("PATH")) {
+		if p != "" && filepath.Clean(p) == u.fDir {
+			onPath = true
+			break
+		}
+	}
+	if !onPath {
+		u.Term().Warning().Printfln("%s is not on PATH, add it to PATH to use the installed %s", u.fDir, u.fName)
+		return
+	}
+
+	found, err := exec.LookPath(u.fName)
+	if err != nil {
+		return
+	}
+	if abs, err := filepath.Abs(found); err == nil {
+		found = abs
+	}
+	if filepath.Dir(found) != u.fDir {
+		u.Term().Warning().Printfln("%s shadows %s on PATH, remove it or move %s before %s on PATH", found, u.fPath, u.fDir, filepath.Dir(found))
+	}
+}
+
+// isVersioned checks if versions are installed side by side and switched with a symlink.
+func (u *updateAction) isVersioned() bool {
+	return u.cfg.InstallLayout == installLayoutVersioned
+}
+
+// versionsDir returns a per-user directory of side by side installed versions,
+// $XDG_DATA_HOME/<app>/versions or ~/.local/share/<app>/versions.
+func (u *updateAction) versionsDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Abs(filepath.Join(dataHome, u.appName, versionsDirName))
+}
+
+// versionPath returns a path of the binary of the installed version.
+func (u *updateAction) versionPath(version string) (string, error) {
+	dir, err := u.versionsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, sanitizeVersion(version), u.fName), nil
+}
+
+// installedVersion returns a path of the binary if the version is installed side by side.
+func (u *updateAction) installedVersion(version string) (string, bool) {
+	path, err := u.versionPath(version)
+	if err != nil {
+		return "", false
+	}
+	if _, err = os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// resolveLinkPath finds the link on PATH if the running binary is one of the installed versions.
+func (u *updateAction) resolveLinkPath() error {
+	dir, err := u.versionsDir()
+	if err != nil {
+		return err
+	}
+	if evalDir, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = evalDir
+	}
+	rel, err := filepath.Rel(dir, u.fPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		// The running binary is replaced with the link on the first install.
+		return nil
+	}
+
+	errNoLink := fmt.Errorf("can't find a link to %s on PATH, use --install-dir to set the link directory", u.fPath)
+	found, err := exec.LookPath(u.fName)
+	if err != nil {
+		return errNoLink
+	}
+	resolved, err := filepath.EvalSymlinks(found)
+	if err != nil || resolved != u.fPath {
+		return errNoLink
+	}
+	found, err = filepath.Abs(found)
+	if err != nil {
+		return err
+	}
+
+	u.fDir = filepath.Dir(found)
+	u.fPath = found
+	return nil
+}
+
+// checkLinkDir checks the link can be switched without privilege escalation.
+// Versions are installed into the user data dir, a link in a shared directory would make
+// everyone running the binary, root included, execute a file the user can modify.
+func (u *updateAction) checkLinkDir() error {
+	err := hasWritePermissions(u.fDir)
+	if err == nil || !errors.Is(err, errNoWritePermission) {
+		return err
+	}
+	return fmt.Errorf("%w: install layout %s links %s to the user data dir, use install_mode: %s or --install-dir", errNoWritePermission, installLayoutVersioned, u.fPath, installModeUser)
+}
+
+// installVersioned installs src to the versions directory and switches the link to it.
+func (u *updateAction) installVersioned(src, version string) error {
+	target, err := u.versionPath(version)
+	if err != nil {
+		return err
+	}
+
+	if src != target {
+		if err = launchr.EnsurePath(filepath.Dir(target)); err != nil {
+			return err
+		}
+		if err = installFileDirect(src, target); err != nil {
+			return err
+		}
+	}
+
+	return u.switchLink(target)
+}
+
+// switchLink atomically points the binary link to the target.
+// A new link is created next to the current one and renamed over it.
+func (u *updateAction) switchLink(target string) error {
+	u.Log().Debug("switching link", "link", u.fPath, "target", target)
+	fTmpName := u.fPath + ".tmp"
+
+	// Mark the version as recently used for the retention policy.
+	now := time.Now()
+	if err := os.Chtimes(filepath.Dir(target), now, now); err != nil {
+		u.Log().Debug("error updating version dir times", "dir", filepath.Dir(target), "error", err)
+	}
+
+	err := os.Remove(fTmpName)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err = os.Symlink(target, fTmpName); err != nil {
+		return err
+	}
+	if err = os.Rename(fTmpName, u.fPath); err != nil {
+		_ = os.Remove(fTmpName)
+		return err
+	}
+
+	return nil
+}
+
+// pruneVersions removes the least recently used versions exceeding the retention limit.
+// The version the link points to and the running one are always kept.
+func (u *updateAction) pruneVersions() {
+	keep := u.keepVersions
+	if keep <= 0 {
+		keep = u.cfg.KeepVersions
+	}
+	if keep <= 0 {
+		keep = defaultKeepVersions
+	}
+
+	root, err := u.versionsDir()
+	if err != nil {
+		u.Log().Error("error getting versions dir", "error", err)
+		return
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		u.Log().Error("error listing versions", "dir", root, "error", err)
+		return
+	}
+
+	type versionDir struct {
+		path    string
+		modTime time.Time
+	}
+	dirs := make([]versionDir, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		dirs = append(dirs, versionDir{path: filepath.Join(root, e.Name()), modTime: info.ModTime()})
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return dirs[i].modTime.After(dirs[j].modTime)
+	})
+
+	current := ""
+	if target, err := filepath.EvalSymlinks(u.fPath); err == nil {
+		current = filepath.Dir(target)
+	}
+	running := filepath.Dir(u.execPath)
+
+	for i := keep; i < len(dirs); i++ {
+		if dirs[i].path == current || dirs[i].path == running {
+			continue
+		}
+		u.Log().Debug("removing old version", "dir", dirs[i].path)
+		if err = os.RemoveAll(dirs[i].path); err != nil {
+			u.Log().Error("error removing version", "dir", dirs[i].path, "error", err)
+		}
+	}
+}
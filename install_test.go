@@ -0,0 +1,47 @@
+package plasmactlupdate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCheckLinkDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("versioned layout isn't supported on windows")
+	}
+
+	writable := t.TempDir()
+	readOnly := t.TempDir()
+	if err := os.Chmod(readOnly, 0500); err != nil { //nolint:gosec // Test directory.
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(readOnly, 0700) }) //nolint:gosec // Test directory.
+	if hasWritePermissions(readOnly) == nil {
+		t.Skip("directory permissions aren't enforced, e.g. for root")
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		wantErr error
+	}{
+		{"writable link dir", writable, nil},
+		// A shared system directory, the link would need privilege escalation.
+		{"link dir requires privileges", readOnly, errNoWritePermission},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &updateAction{
+				cfg:   &config{InstallLayout: installLayoutVersioned},
+				fDir:  tt.dir,
+				fPath: filepath.Join(tt.dir, "app"),
+			}
+			if err := u.checkLinkDir(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("checkLinkDir() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package plasmactlupdate
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	releaseFormatText     = "text"
+	releaseFormatManifest = "manifest"
+)
+
+// releaseManifest is a structured release document listing available versions.
+// Both JSON and YAML documents are supported.
+type releaseManifest struct {
+	Latest   string            `yaml:"latest"`
+	Versions []manifestRelease `yaml:"versions"`
+}
+
+// manifestRelease describes a single released version.
+type manifestRelease struct {
+	Version    string             `yaml:"version"`
+	Notes      string             `yaml:"notes"`
+	MinVersion string             `yaml:"min_version"`
+	Deprecated bool               `yaml:"deprecated"`
+	Artifacts  []manifestArtifact `yaml:"artifacts"`
+}
+
+// manifestArtifact describes a binary of a version for a specific platform.
+type manifestArtifact struct {
+	OS     string `yaml:"os"`
+	Arch   string `yaml:"arch"`
+	URL    string `yaml:"url"`
+	SHA256 string `yaml:"sha256"`
+	Size   int64  `yaml:"size"`
+}
+
+// findRelease returns a release of the given version.
+func (m *releaseManifest) findRelease(version string) (*manifestRelease, error) {
+	for i := range m.Versions {
+		if m.Versions[i].Version == version {
+			return &m.Versions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("version %s not found in release manifest", version)
+}
+
+// latestVersion returns the version to install by default.
+// When "latest" isn't set, the highest non-deprecated semver version is used.
+func (m *releaseManifest) latestVersion() (string, error) {
+	if m.Latest != "" {
+		return m.Latest, nil
+	}
+
+	var latest *semver
+	var res string
+	for _, r := range m.Versions {
+		v, ok := parseSemver(r.Version)
+		if !ok || r.Deprecated {
+			continue
+		}
+		if latest == nil || v.compare(latest) > 0 {
+			latest = v
+			res = r.Version
+		}
+	}
+	if res == "" {
+		return "", fmt.Errorf("release manifest doesn't have a latest version")
+	}
+
+	return res, nil
+}
+
+// findArtifact returns an artifact for the os and arch.
+// Both updater names (Linux, x86_64) and Go names (linux, amd64) are accepted.
+func (r *manifestRelease) findArtifact(osName, arch string) (*manifestArtifact, error) {
+	for i := range r.Artifacts {
+		a := &r.Artifacts[i]
+		osMatch := strings.EqualFold(a.OS, osName) || strings.EqualFold(a.OS, runtime.GOOS)
+		archMatch := strings.EqualFold(a.Arch, arch) || strings.EqualFold(a.Arch, runtime.GOARCH)
+		if osMatch && archMatch {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("version %s doesn't have an artifact for %s/%s", r.Version, osName, arch)
+}
+
+// isManifestMode checks if releases are described by a manifest.
+func (u *updateAction) isManifestMode() bool {
+	return u.cfg.ReleaseFormat == releaseFormatManifest
+}
+
+// fetchManifest downloads and parses the release manifest.
+func (u *updateAction) fetchManifest() (*releaseManifest, error) {
+	manifestURL, err := formatURL(u.cfg.PinnedRelease, u.newTemplateVars(""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format release manifest URL: %w", err)
+	}
+
+	resp, err := u.sendRequest(manifestURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is a subset of YAML, parse both the same way.
+	m := &releaseManifest{}
+	if err = yaml.Unmarshal(body, m); err != nil {
+		return nil, fmt.Errorf("failed to parse release manifest: %w", err)
+	}
+
+	// Resolve relative artifact URLs against the manifest location.
+	base, err := url.Parse(manifestURL)
+	if err != nil {
+		return nil, err
+	}
+	for i := range m.Versions {
+		for j := range m.Versions[i].Artifacts {
+			a := &m.Versions[i].Artifacts[j]
+			ref, err := url.Parse(a.URL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid artifact URL %q: %w", a.URL, err)
+			}
+			a.URL = base.ResolveReference(ref).String()
+		}
+	}
+
+	u.manifest = m
+	u.manifestMirror = u.source.baseURL()
+	return m, nil
+}
+
+// resolveManifestArtifact selects the release and artifact to install and reports its state.
+func (u *updateAction) resolveManifestArtifact(current, version string) error {
+	latest, err := u.manifest.latestVersion()
+	if err == nil {
+		if r, errFind := u.manifest.findRelease(latest); errFind == nil && r.MinVersion != "" {
+			if c := compareVersions(current, r.MinVersion); c == changeUpgrade {
+				u.Term().Warning().Printfln("Current version %s is no longer supported, minimum supported version is %s", current, r.MinVersion)
+			}
+		}
+	}
+
+	if r, errFind := u.manifest.findRelease(current); errFind == nil && r.Deprecated {
+		u.Term().Warning().Printfln("Current version %s is deprecated", current)
+	}
+
+	r, err := u.manifest.findRelease(version)
+	if err != nil {
+		return err
+	}
+	if r.Deprecated {
+		u.Term().Warning().Printfln("Version %s is deprecated", r.Version)
+	}
+	if r.Notes != "" {
+		u.Term().Printfln("Release notes for %s:\n%s", r.Version, strings.TrimSpace(r.Notes))
+	}
+
+	return u.selectManifestArtifact(version)
+}
+
+// selectManifestArtifact selects the artifact of the version for the current platform.
+func (u *updateAction) selectManifestArtifact(version string) error {
+	r, err := u.manifest.findRelease(version)
+	if err != nil {
+		return err
+	}
+	a, err := r.findArtifact(u.os, u.arch)
+	if err != nil {
+		return err
+	}
+	if err = validateURL(a.URL); err != nil {
+		return fmt.Errorf("invalid artifact URL in release manifest: %w", err)
+	}
+
+	u.artifact = a
+	return nil
+}
+
+// refreshManifestArtifact fetches the manifest again after the mirror was switched,
+// so the artifact is downloaded from the current mirror.
+func (u *updateAction) refreshManifestArtifact(version string) error {
+	if u.manifest == nil || u.manifestMirror == u.source.baseURL() {
+		return nil
+	}
+
+	u.Log().Debug("mirror switched, fetching release manifest again", "mirror", u.source.baseURL())
+	u.artifact = nil
+	if _, err := u.fetchManifest(); err != nil {
+		return err
+	}
+	return u.selectManifestArtifact(version)
+}
+
+// verifyArtifact checks size and digest of the downloaded file against the manifest.
+func (u *updateAction) verifyArtifact() error {
+	if u.artifact.Size > 0 {
+		fi, err := os.Stat(u.fDownloadPath)
+		if err != nil {
+			return err
+		}
+		if fi.Size() != u.artifact.Size {
+			return fmt.Errorf("size mismatch: expected %d bytes, got %d", u.artifact.Size, fi.Size())
+		}
+	}
+
+	if u.artifact.SHA256 == "" {
+		return nil
+	}
+
+	expected, err := validateDigest(u.artifact.SHA256)
+	if err != nil {
+		return err
+	}
+	actual, err := fileSHA256(u.fDownloadPath)
+	if err != nil {
+		return err
+	}
+	if expected != actual {
+		return fmt.Errorf("%w: expected %s, got %s", errChecksumMismatch, expected, actual)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package plasmactlupdate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testManifest(binary string) string {
+	sum := sha256.Sum256([]byte(binary))
+	return `latest: 1.2.0
+versions:
+  - version: 1.2.0
+    artifacts:
+      - os: Linux
+        arch: x86_64
+        url: 1.2.0/app_Linux_x86_64
+        sha256: ` + hex.EncodeToString(sum[:]) + `
+`
+}
+
+func TestManifestMirrorFailover(t *testing.T) {
+	const binary = "binary from mirror 2"
+
+	// The first mirror serves the manifest but lost the binary.
+	mirror1 := httptest.NewServer(serveFiles(map[string]string{
+		"/manifest.yaml": testManifest(binary),
+	}))
+	defer mirror1.Close()
+	mirror2 := httptest.NewServer(serveFiles(map[string]string{
+		"/manifest.yaml":          testManifest(binary),
+		"/1.2.0/app_Linux_x86_64": binary,
+	}))
+	defer mirror2.Close()
+
+	u := newTestAction(t, mirror1, &config{
+		RepositoryURLs: []mirror{{URL: mirror1.URL}, {URL: mirror2.URL}},
+		PinnedRelease:  "{{.URL}}/manifest.yaml",
+		ReleaseFormat:  releaseFormatManifest,
+	})
+	u.fDownloadPath = filepath.Join(t.TempDir(), "app")
+
+	version, err := u.resolveVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = u.resolveManifestArtifact("1.0.0", version); err != nil {
+		t.Fatal(err)
+	}
+	if want := mirror1.URL + "/1.2.0/app_Linux_x86_64"; u.artifact.URL != want {
+		t.Fatalf("artifact URL %q, want %q", u.artifact.URL, want)
+	}
+
+	err = u.withFailover(func() error {
+		return u.downloadFile(version)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mirror2.URL + "/1.2.0/app_Linux_x86_64"; u.binURL != want {
+		t.Fatalf("downloaded from %q, want %q", u.binURL, want)
+	}
+	if err = u.verifyArtifact(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(u.fDownloadPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != binary {
+		t.Fatalf("downloaded %q", data)
+	}
+}
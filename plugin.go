@@ -18,6 +18,9 @@ func init() {
 //go:embed action.yaml
 var actionYaml []byte
 
+//go:embed action.rollback.yaml
+var actionRollbackYaml []byte
+
 // Plugin is [launchr.Plugin] providing update action.
 type Plugin struct {
 	k keyring.Keyring
@@ -33,6 +36,7 @@ func (p *Plugin) PluginInfo() launchr.PluginInfo {
 // OnAppInit implements [launchr.OnAppInitPlugin] interface.
 func (p *Plugin) OnAppInit(app launchr.App) error {
 	app.GetService(&p.k)
+	cleanupPreviousUpdate()
 	return nil
 }
 
@@ -46,21 +50,12 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 			Username: input.Opt("username").(string),
 			Password: input.Opt("password").(string),
 		}
-
-		log := launchr.Log()
-		if rt, ok := a.Runtime().(action.RuntimeLoggerAware); ok {
-			log = rt.LogWith()
-		}
-
-		term := launchr.Term()
-		if rt, ok := a.Runtime().(action.RuntimeTermAware); ok {
-			term = rt.Term()
+		if token := input.Opt("token").(string); token != "" {
+			ci.Password = token
 		}
 
-		cmd, err := getUpdateCmd()
-		if err != nil {
-			return err
-		}
+		channel := input.Opt("channel").(string)
+		pinnedOverride := false
 
 		var cfg *config
 		// Check if the user submitted a custom config file.
@@ -77,15 +72,44 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 			repoURL := input.Opt("repository-url").(string)
 			if repoURL != "" {
 				cfg.RepositoryURL = repoURL
+				cfg.RepositoryURLs = nil
 			}
 			pinnedRelease := input.Opt("release-file-mask").(string)
 			if pinnedRelease != "" {
+				if channel != "" {
+					return fmt.Errorf("options channel and release-file-mask can't be used together")
+				}
 				cfg.PinnedRelease = pinnedRelease
+				pinnedOverride = true
 			}
 			binMask := input.Opt("bin-mask").(string)
 			if binMask != "" {
 				cfg.BinMask = binMask
 			}
+			checksumMask := input.Opt("checksum-mask").(string)
+			if checksumMask != "" {
+				cfg.ChecksumMask = checksumMask
+			}
+			signatureMask := input.Opt("signature-mask").(string)
+			if signatureMask != "" {
+				cfg.SignatureMask = signatureMask
+			}
+			proxyURL := input.Opt("proxy-url").(string)
+			if proxyURL != "" {
+				cfg.ProxyURL = proxyURL
+			}
+			noProxy := input.Opt("no-proxy").(string)
+			if noProxy != "" {
+				cfg.NoProxy = noProxy
+			}
+			authType := input.Opt("auth-type").(string)
+			if authType != "" {
+				cfg.Auth.Type = authType
+			}
+			authHeader := input.Opt("auth-header").(string)
+			if authHeader != "" {
+				cfg.Auth.Header = authHeader
+			}
 		}
 
 		// Fallback to default config values if they are empty.
@@ -95,24 +119,68 @@ func (p *Plugin) DiscoverActions(_ context.Context) ([]*action.Action, error) {
 		if cfg.BinMask == "" {
 			cfg.BinMask = defaultBinTpl
 		}
+		if cfg.SmokeCommand == "" {
+			cfg.SmokeCommand = defaultSmokeCommand
+		}
 
 		u := &updateAction{
-			k:             p.k,
-			credentials:   ci,
-			sudoCmd:       cmd,
-			cfg:           cfg,
-			targetVersion: input.Opt("target").(string),
+			k:              p.k,
+			credentials:    ci,
+			installDir:     input.Opt("install-dir").(string),
+			keepVersions:   input.Opt("keep").(int),
+			cfg:            cfg,
+			targetVersion:  input.Opt("target").(string),
+			checkOnly:      input.Opt("check").(bool),
+			nonInteractive: input.Opt("non-interactive").(bool),
+			allowDowngrade: input.Opt("allow-downgrade").(bool),
+			channel:        channel,
+			pinnedOverride: pinnedOverride,
 		}
-		u.SetLogger(log)
-		u.SetTerm(term)
+		setActionIO(u, a)
 
-		err = u.doRun()
-		if err != nil {
+		err := u.doRun()
+		if err != nil && !u.checkOnly {
 			u.Term().Error().Println("Update failed")
-			u.cleanup()
+		}
+		u.cleanup()
+
+		return err
+	}))
+
+	rollback := action.NewFromYAML("update:rollback", actionRollbackYaml)
+	rollback.SetRuntime(action.NewFnRuntime(func(_ context.Context, a *action.Action) error {
+		input := a.Input()
+
+		u := &updateAction{
+			k:          p.k,
+			installDir: input.Opt("install-dir").(string),
+			cfg:        getUpdateConfig(),
+		}
+		setActionIO(u, a)
+
+		err := u.doRollback(input.Opt("version").(string), input.Opt("list").(bool))
+		if err != nil {
+			u.Term().Error().Println("Rollback failed")
 		}
 
 		return err
 	}))
-	return []*action.Action{a}, nil
+
+	return []*action.Action{a, rollback}, nil
+}
+
+// setActionIO sets logger and terminal of the action runtime.
+func setActionIO(u *updateAction, a *action.Action) {
+	log := launchr.Log()
+	if rt, ok := a.Runtime().(action.RuntimeLoggerAware); ok {
+		log = rt.LogWith()
+	}
+
+	term := launchr.Term()
+	if rt, ok := a.Runtime().(action.RuntimeTermAware); ok {
+		term = rt.Term()
+	}
+
+	u.SetLogger(log)
+	u.SetTerm(term)
 }
@@ -0,0 +1,166 @@
+package plasmactlupdate
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// proxyFunc returns a proxy selector for the shared HTTP transport.
+// Configured proxy_url and no_proxy have priority over the environment variables.
+func (u *updateAction) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	noProxy := u.cfg.NoProxy
+	if noProxy == "" {
+		noProxy = getEnvAny("NO_PROXY", "no_proxy")
+	}
+
+	if u.cfg.ProxyURL == "" {
+		if u.cfg.NoProxy == "" {
+			return http.ProxyFromEnvironment, nil
+		}
+
+		// Configured no_proxy replaces NO_PROXY, proxies are still taken from the environment.
+		proxies, err := envProxyURLs()
+		if err != nil {
+			return nil, err
+		}
+		return func(req *http.Request) (*url.URL, error) {
+			if matchNoProxy(noProxy, req.URL) {
+				return nil, nil
+			}
+			return proxies[req.URL.Scheme], nil
+		}, nil
+	}
+
+	proxyURL, err := parseProxyURL(u.cfg.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		if matchNoProxy(noProxy, req.URL) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// envProxyURLs returns proxy URLs per request scheme from HTTP_PROXY and HTTPS_PROXY variables.
+func envProxyURLs() (map[string]*url.URL, error) {
+	proxies := make(map[string]*url.URL)
+	for scheme, raw := range map[string]string{
+		"http":  getEnvAny("HTTP_PROXY", "http_proxy"),
+		"https": getEnvAny("HTTPS_PROXY", "https_proxy"),
+	} {
+		if raw == "" {
+			continue
+		}
+		p, err := parseProxyURL(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s proxy from environment: %w", scheme, err)
+		}
+		proxies[scheme] = p
+	}
+	return proxies, nil
+}
+
+// parseProxyURL parses a proxy URL, "http" scheme is used if it's omitted.
+func parseProxyURL(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	p, err := url.Parse(raw)
+	if err != nil {
+		// Don't leak credentials of the proxy URL in the error.
+		return nil, fmt.Errorf("invalid proxy URL")
+	}
+	switch p.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL scheme: %s (expected http, https or socks5)", p.Scheme)
+	}
+	if p.Host == "" {
+		return nil, fmt.Errorf("proxy URL missing host")
+	}
+	return p, nil
+}
+
+// redactProxyURL returns the proxy URL without credentials to be logged.
+func redactProxyURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	p, err := parseProxyURL(raw)
+	if err != nil {
+		return "<invalid>"
+	}
+	return p.Redacted()
+}
+
+// matchNoProxy checks if the URL host matches comma separated no_proxy list.
+// Entries may be "*", hosts, domains with or without leading dot, host:port pairs and CIDR ranges.
+func matchNoProxy(noProxy string, u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = defaultPort(u.Scheme)
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		}
+
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort, err := net.SplitHostPort(entry)
+		if err != nil {
+			entryHost, entryPort = entry, ""
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+
+		entryHost = strings.TrimPrefix(entryHost, "*")
+		domain := strings.TrimPrefix(entryHost, ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// defaultPort returns the port implied by the URL scheme.
+func defaultPort(scheme string) string {
+	switch strings.ToLower(scheme) {
+	case "https":
+		return "443"
+	case "http":
+		return "80"
+	default:
+		return ""
+	}
+}
+
+func getEnvAny(names ...string) string {
+	for _, n := range names {
+		if v := os.Getenv(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package plasmactlupdate
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestMatchNoProxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		noProxy string
+		url     string
+		want    bool
+	}{
+		{"empty", "", "https://repo.example.com", false},
+		{"wildcard", "*", "https://repo.example.com", true},
+		{"exact host", "repo.example.com", "https://repo.example.com/app", true},
+		{"host is case-insensitive", "Repo.Example.com", "https://repo.example.COM", true},
+		{"other host", "repo.example.com", "https://cdn.example.com", false},
+		{"domain matches subdomain", "example.com", "https://repo.example.com", true},
+		{"domain doesn't match suffix", "example.com", "https://badexample.com", false},
+		{"leading dot", ".example.com", "https://repo.example.com", true},
+		{"leading dot matches domain", ".example.com", "https://example.com", true},
+		{"leading wildcard", "*.example.com", "https://repo.example.com", true},
+		{"list with spaces", "localhost, .internal ,repo.example.com", "https://repo.example.com", true},
+		{"explicit port", "repo.example.com:8443", "https://repo.example.com:8443", true},
+		{"other port", "repo.example.com:8443", "https://repo.example.com:9443", false},
+		{"port entry and implicit https port", "repo.example.com:443", "https://repo.example.com", true},
+		{"port entry and implicit http port", "repo.example.com:80", "http://repo.example.com/app", true},
+		{"port entry and other scheme port", "repo.example.com:443", "http://repo.example.com", false},
+		{"cidr", "10.0.0.0/8", "https://10.1.2.3/app", true},
+		{"cidr other network", "10.0.0.0/8", "https://192.168.1.1", false},
+		{"cidr doesn't match host name", "10.0.0.0/8", "https://repo.example.com", false},
+		{"ipv6 cidr", "fd00::/8", "https://[fd00::1]:8443", true},
+		{"ip", "192.168.1.1", "http://192.168.1.1:8080", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := matchNoProxy(tt.noProxy, u); got != tt.want {
+				t.Errorf("matchNoProxy(%q, %s) = %t, want %t", tt.noProxy, tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxyURL string
+		noProxy  string
+		env      map[string]string
+		url      string
+		want     string
+	}{
+		{
+			name:    "configured no_proxy with environment proxy",
+			noProxy: ".internal",
+			env:     map[string]string{"HTTPS_PROXY": "http://env-proxy:3128"},
+			url:     "https://repo.example.com",
+			want:    "http://env-proxy:3128",
+		},
+		{
+			name:    "configured no_proxy bypasses environment proxy",
+			noProxy: ".internal",
+			env:     map[string]string{"HTTPS_PROXY": "http://env-proxy:3128"},
+			url:     "https://repo.internal",
+		},
+		{
+			name:    "configured no_proxy replaces environment NO_PROXY",
+			noProxy: ".internal",
+			env:     map[string]string{"HTTPS_PROXY": "http://env-proxy:3128", "NO_PROXY": "repo.example.com"},
+			url:     "https://repo.example.com",
+			want:    "http://env-proxy:3128",
+		},
+		{
+			name:    "proxy of the request scheme",
+			noProxy: ".internal",
+			env:     map[string]string{"HTTPS_PROXY": "http://env-proxy:3128"},
+			url:     "http://repo.example.com",
+		},
+		{
+			name:     "configured proxy with environment NO_PROXY",
+			proxyURL: "proxy.example.com:3128",
+			env:      map[string]string{"NO_PROXY": "repo.internal:443"},
+			url:      "https://repo.internal",
+		},
+		{
+			name:     "configured proxy",
+			proxyURL: "proxy.example.com:3128",
+			noProxy:  ".internal",
+			url:      "https://repo.example.com",
+			want:     "http://proxy.example.com:3128",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Lowercase names go first, names are case-insensitive on windows.
+			for _, name := range []string{"http_proxy", "HTTP_PROXY", "https_proxy", "HTTPS_PROXY", "no_proxy", "NO_PROXY"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			u := &updateAction{cfg: &config{ProxyURL: tt.proxyURL, NoProxy: tt.noProxy}}
+			proxy, err := u.proxyFunc()
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := proxy(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if p != nil {
+				got = p.String()
+			}
+			if got != tt.want {
+				t.Errorf("proxy %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package plasmactlupdate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/launchrctl/launchr"
+)
+
+const (
+	defaultSmokeCommand = "version"
+	defaultSmokeTimeout = 30 * time.Second
+
+	backupsDirName = "backups"
+	backupMetaName = "backup.json"
+	backupsKeep    = 3
+)
+
+var errHealthCheckFailed = errors.New("post-install health check failed")
+
+// backup describes a previously installed binary kept locally.
+type backup struct {
+	Version     string    `json:"version"`
+	Name        string    `json:"name"`
+	Path        string    `json:"path"`
+	InstalledAt time.Time `json:"installed_at"`
+	BackedUpAt  time.Time `json:"backed_up_at"`
+	SHA256      string    `json:"sha256"`
+
+	dir string
+}
+
+// binPath returns a path of the backed up binary.
+func (b *backup) binPath() string {
+	return filepath.Join(b.dir, b.Name)
+}
+
+// backupsDir returns a per-user directory where backups are stored.
+func (u *updateAction) backupsDir() (string, error) {
+	dir, err := userDataDir(u.appName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, backupsDirName), nil
+}
+
+// createBackup copies the currently installed binary to the backups directory.
+func (u *updateAction) createBackup() (*backup, error) {
+	root, err := u.backupsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	// The running binary is backed up, in user install mode there may be no installed one yet.
+	fi, err := os.Stat(u.execPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sum, err := fileSHA256(u.execPath)
+	if err != nil {
+		return nil, err
+	}
+
+	version := launchr.Version().Version
+	b := &backup{
+		Version:     version,
+		Name:        u.fName,
+		Path:        u.execPath,
+		InstalledAt: fi.ModTime(),
+		BackedUpAt:  time.Now(),
+		SHA256:      sum,
+		dir:         filepath.Join(root, sanitizeVersion(version)),
+	}
+
+	u.Log().Debug("creating backup", "version", b.Version, "dir", b.dir)
+	if err = os.RemoveAll(b.dir); err != nil {
+		return nil, err
+	}
+	if err = copyFile(u.execPath, b.binPath()); err != nil {
+		return nil, fmt.Errorf("failed to backup %s: %w", u.execPath, err)
+	}
+	if err = os.Chmod(b.binPath(), 0700); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(filepath.Join(b.dir, backupMetaName), data, 0600); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// listBackups returns local backups sorted from the most recent one.
+func (u *updateAction) listBackups() ([]*backup, error) {
+	root, err := u.backupsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	backups := make([]*backup, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, e.Name())
+		data, err := os.ReadFile(filepath.Join(dir, backupMetaName)) //nolint:gosec // Path is built from backups dir.
+		if err != nil {
+			u.Log().Debug("skipping malformed backup", "dir", dir, "error", err)
+			continue
+		}
+		b := &backup{}
+		if err = json.Unmarshal(data, b); err != nil {
+			u.Log().Debug("skipping malformed backup", "dir", dir, "error", err)
+			continue
+		}
+		b.dir = dir
+		backups = append(backups, b)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].BackedUpAt.After(backups[j].BackedUpAt)
+	})
+
+	return backups, nil
+}
+
+// pruneBackups removes the oldest backups exceeding the retention limit.
+func (u *updateAction) pruneBackups() {
+	backups, err := u.listBackups()
+	if err != nil {
+		u.Log().Error("error listing backups", "error", err)
+		return
+	}
+	for i := backupsKeep; i < len(backups); i++ {
+		u.Log().Debug("removing old backup", "version", backups[i].Version, "dir", backups[i].dir)
+		if err = os.RemoveAll(backups[i].dir); err != nil {
+			u.Log().Error("error removing backup", "dir", backups[i].dir, "error", err)
+		}
+	}
+}
+
+// restoreBackup installs the backed up binary in place of the current one.
+func (u *updateAction) restoreBackup(b *backup) error {
+	// Switch back instantly if the version is still installed side by side.
+	if u.isVersioned() {
+		if target, ok := u.installedVersion(b.Version); ok {
+			u.Term().Printfln("Switching %s to installed version %s", b.Name, b.Version)
+			return u.switchLink(target)
+		}
+	}
+
+	sum, err := fileSHA256(b.binPath())
+	if err != nil {
+		return err
+	}
+	if sum != b.SHA256 {
+		return fmt.Errorf("%w: backup %s is corrupted", errChecksumMismatch, b.Version)
+	}
+
+	u.Term().Printfln("Restoring %s version %s", b.Name, b.Version)
+	return u.installFile(b.binPath(), b.Version)
+}
+
+// healthCheck runs the installed binary with the smoke command and checks it exits successfully.
+func (u *updateAction) healthCheck() error {
+	args := strings.Fields(u.cfg.SmokeCommand)
+	timeout := u.cfg.SmokeTimeout
+	if timeout <= 0 {
+		timeout = defaultSmokeTimeout
+	}
+
+	u.Term().Printfln("Running health check: %s %s", u.fName, strings.Join(args, " "))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, u.fPath, args...) //nolint:gosec // Binary path is resolved from the running executable.
+	cmd.WaitDelay = time.Second
+	out, err := cmd.CombinedOutput()
+	u.Log().Debug("health check output", "output", string(out))
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: timed out after %s", errHealthCheckFailed, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", errHealthCheckFailed, err)
+	}
+
+	return nil
+}
+
+// installWithRollback installs the downloaded binary and restores the previous one
+// if the installation or the health check fails.
+func (u *updateAction) installWithRollback(version string) error {
+	b, err := u.createBackup()
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	if err = u.installFile(u.fTmpPath, version); err != nil {
+		return err
+	}
+
+	if err = u.healthCheck(); err != nil {
+		u.Term().Warning().Printfln("%s, rolling back to %s", err, b.Version)
+		if errRestore := u.restoreBackup(b); errRestore != nil {
+			return fmt.Errorf("%w, rollback failed: %w", err, errRestore)
+		}
+		return err
+	}
+
+	u.pruneBackups()
+	return nil
+}
+
+// sanitizeVersion makes the version safe to use as a directory name.
+func sanitizeVersion(v string) string {
+	if v == "" {
+		return "unknown"
+	}
+	return strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(v)
+}
+
+// doRollback lists local backups or restores the chosen one.
+func (u *updateAction) doRollback(version string, list bool) error {
+	u.appName = launchr.Version().Name
+	current := launchr.Version().Version
+
+	if err := u.findExecPaths(); err != nil {
+		return err
+	}
+
+	backups, err := u.listBackups()
+	if err != nil {
+		return err
+	}
+
+	if list {
+		u.printBackups(backups, current)
+		return nil
+	}
+
+	b, err := selectBackup(backups, version, current)
+	if err != nil {
+		return err
+	}
+
+	// Keep the current binary to be able to switch back.
+	if _, err = u.createBackup(); err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	if err = u.restoreBackup(b); err != nil {
+		return err
+	}
+	u.pruneBackups()
+
+	u.Term().Success().Printfln("%s has been rolled back to %s.", u.fName, b.Version)
+	u.warnInstallDirPath()
+	return nil
+}
+
+// selectBackup finds a backup by version or the most recent one different from the current version.
+func selectBackup(backups []*backup, version, current string) (*backup, error) {
+	if version == current && version != "" {
+		return nil, fmt.Errorf("version %s is currently installed", version)
+	}
+
+	for _, b := range backups {
+		if version != "" && b.Version == version {
+			return b, nil
+		}
+		if version == "" && b.Version != current {
+			return b, nil
+		}
+	}
+
+	if version != "" {
+		return nil, fmt.Errorf("backup of version %s not found", version)
+	}
+
+	return nil, fmt.Errorf("no backups available to restore")
+}
+
+// printBackups outputs the list of backups.
+func (u *updateAction) printBackups(backups []*backup, current string) {
+	if len(backups) == 0 {
+		u.Term().Printfln("No backups found.")
+		return
+	}
+
+	u.Term().Printfln("%-3s%-24s%-22s%s", "", "VERSION", "INSTALLED", "SHA256")
+	for _, b := range backups {
+		mark := ""
+		if b.Version == current {
+			mark = "*"
+		}
+		u.Term().Printfln("%-3s%-24s%-22s%s", mark, b.Version, b.InstalledAt.Format(time.DateTime), b.SHA256)
+	}
+}
@@ -0,0 +1,165 @@
+package plasmactlupdate
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRollbackTestAction returns an update action installing in place of the current script binary.
+// Backups are stored in a temp user config dir.
+func newRollbackTestAction(t *testing.T, current, next string) *updateAction {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are used as binaries")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+	dir := t.TempDir()
+	u := &updateAction{
+		cfg:      &config{SmokeCommand: defaultSmokeCommand, SmokeTimeout: 5 * time.Second},
+		appName:  "app",
+		fName:    "app",
+		fDir:     dir,
+		fPath:    filepath.Join(dir, "app"),
+		fTmpPath: filepath.Join(t.TempDir(), "app"),
+	}
+	u.execPath = u.fPath
+	writeScript(t, u.fPath, current)
+	writeScript(t, u.fTmpPath, next)
+
+	return u
+}
+
+func writeScript(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil { //nolint:gosec // Test binary must be executable.
+		t.Fatal(err)
+	}
+}
+
+func assertScript(t *testing.T, path, body string) {
+	t.Helper()
+	data, err := os.ReadFile(path) //nolint:gosec // Test path.
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "#!/bin/sh\n" + body + "\n"; string(data) != want {
+		t.Errorf("binary is %q, want %q", data, want)
+	}
+}
+
+func TestInstallWithRollbackRestoresOnFailure(t *testing.T) {
+	u := newRollbackTestAction(t, "exit 0", "exit 3")
+
+	err := u.installWithRollback("2.0.0")
+	if !errors.Is(err, errHealthCheckFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertScript(t, u.fPath, "exit 0")
+}
+
+func TestInstallWithRollbackRestoresOnTimeout(t *testing.T) {
+	u := newRollbackTestAction(t, "exit 0", "sleep 10")
+	u.cfg.SmokeTimeout = 200 * time.Millisecond
+
+	start := time.Now()
+	err := u.installWithRollback("2.0.0")
+	if !errors.Is(err, errHealthCheckFailed) || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("health check took %s", elapsed)
+	}
+	assertScript(t, u.fPath, "exit 0")
+}
+
+func TestInstallWithRollbackPrunesBackups(t *testing.T) {
+	u := newRollbackTestAction(t, "exit 0", `[ "$1" = version ] || exit 1`)
+
+	root, err := u.backupsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	for i, v := range []string{"0.1.0", "0.2.0", "0.3.0", "0.4.0"} {
+		b := &backup{Version: v, Name: u.fName, BackedUpAt: old.Add(time.Duration(i) * time.Minute)}
+		data, errJSON := json.Marshal(b)
+		if errJSON != nil {
+			t.Fatal(errJSON)
+		}
+		dir := filepath.Join(root, v)
+		if err = os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, backupMetaName), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = u.installWithRollback("2.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	assertScript(t, u.fPath, `[ "$1" = version ] || exit 1`)
+
+	backups, err := u.listBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != backupsKeep {
+		t.Fatalf("%d backups kept, want %d", len(backups), backupsKeep)
+	}
+	// The backup of the replaced binary is the most recent one, the oldest are removed.
+	if got := backups[backupsKeep-1].Version; got != "0.3.0" {
+		t.Errorf("oldest kept backup %s, want 0.3.0", got)
+	}
+	if _, err = os.Stat(filepath.Join(root, "0.1.0")); !os.IsNotExist(err) {
+		t.Errorf("oldest backup isn't removed: %v", err)
+	}
+}
+
+func TestSelectBackup(t *testing.T) {
+	// Backups are sorted from the most recent one.
+	backups := []*backup{{Version: "1.2.0"}, {Version: "1.1.0"}, {Version: "1.0.0"}}
+
+	tests := []struct {
+		name    string
+		backups []*backup
+		version string
+		current string
+		want    string
+		wantErr string
+	}{
+		{"most recent", backups, "", "1.3.0", "1.2.0", ""},
+		{"default skips current", backups, "", "1.2.0", "1.1.0", ""},
+		{"explicit version", backups, "1.0.0", "1.2.0", "1.0.0", ""},
+		{"explicit missing version", backups, "0.9.0", "1.2.0", "", "backup of version 0.9.0 not found"},
+		{"explicit current version", backups, "1.2.0", "1.2.0", "", "version 1.2.0 is currently installed"},
+		{"only current backed up", []*backup{{Version: "1.2.0"}}, "", "1.2.0", "", "no backups available to restore"},
+		{"no backups", nil, "", "1.2.0", "", "no backups available to restore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := selectBackup(tt.backups, tt.version, tt.current)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.Version != tt.want {
+				t.Errorf("selected %s, want %s", b.Version, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package plasmactlupdate
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errInvalidSignature = errors.New("signature verification failed")
+
+// Signature and key formats.
+//
+// A public key is a base64 encoded raw 32 bytes Ed25519 public key.
+// A signature file contains a base64 encoded raw 64 bytes Ed25519 signature
+// made over the whole binary file. Empty lines and lines starting with "#"
+// are ignored in both. The minisign format isn't supported.
+//
+// They can be produced in CI with openssl:
+//
+//	openssl genpkey -algorithm ed25519 -out release.key
+//	openssl pkey -in release.key -pubout -outform DER | tail -c 32 | base64
+//	openssl pkeyutl -sign -rawin -inkey release.key -in app_Linux_x86_64 | base64 -w0 > app_Linux_x86_64.sig
+
+// parsePublicKey decodes a base64 encoded Ed25519 public key.
+func parsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := decodeBase64Payload(strings.NewReader(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key: expected %d bytes, got %d", ed25519.PublicKeySize, len(b))
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// parsePublicKeys decodes all public keys from the list.
+func parsePublicKeys(keys []string) ([]ed25519.PublicKey, error) {
+	res := make([]ed25519.PublicKey, 0, len(keys))
+	for i, k := range keys {
+		pk, err := parsePublicKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("public_keys[%d]: %w", i, err)
+		}
+		res = append(res, pk)
+	}
+	return res, nil
+}
+
+// decodeBase64Payload reads base64 data skipping comment lines.
+func decodeBase64Payload(r io.Reader) ([]byte, error) {
+	var sb strings.Builder
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sb.WriteString(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(sb.String())
+}
+
+// verifySignature downloads a detached signature for the given version and
+// verifies the downloaded binary with the configured public keys.
+func (u *updateAction) verifySignature(version string) error {
+	keys, err := parsePublicKeys(u.cfg.PublicKeys)
+	if err != nil {
+		return err
+	}
+
+	signatureURL, err := u.releaseFileURL(u.cfg.SignatureMask, version)
+	if err != nil {
+		return fmt.Errorf("failed to format signature URL: %w", err)
+	}
+
+	u.Term().Printfln("Verifying signature: %s", signatureURL)
+	resp, err := u.sendRequest(signatureURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	i, err := verifyFileSignature(u.fDownloadPath, resp.Body, keys)
+	if err != nil {
+		return err
+	}
+	u.Log().Debug("signature verified", "key_index", i)
+
+	return nil
+}
+
+// verifyFileSignature verifies the file with the signature read from r.
+// Returns an index of the key the signature was made with.
+func verifyFileSignature(path string, r io.Reader, keys []ed25519.PublicKey) (int, error) {
+	sig, err := decodeBase64Payload(r)
+	if err != nil {
+		return -1, fmt.Errorf("invalid signature file: %w", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return -1, fmt.Errorf("invalid signature file: expected %d bytes, got %d", ed25519.SignatureSize, len(sig))
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return -1, err
+	}
+
+	for i, pk := range keys {
+		if ed25519.Verify(pk, data, sig) {
+			return i, nil
+		}
+	}
+
+	return -1, errInvalidSignature
+}
@@ -0,0 +1,141 @@
+package plasmactlupdate
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.StdEncoding.EncodeToString(pub)
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{"raw key", enc, false},
+		{"comment and spaces", "# release key\n  " + enc + "  \n", false},
+		{"not base64", "not a key!", true},
+		{"short key", base64.StdEncoding.EncodeToString(pub[:16]), true},
+		// minisign public key payload: algorithm, key id and key.
+		{"minisign key", base64.StdEncoding.EncodeToString(append([]byte("Ed12345678"), pub...)), true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pk, err := parsePublicKey(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && !pk.Equal(pub) {
+				t.Fatalf("unexpected key %x", pk)
+			}
+		})
+	}
+}
+
+func TestDecodeBase64Payload(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"single line", "aGVsbG8=", "hello", false},
+		{"wrapped lines", "aGVs\nbG8=\n", "hello", false},
+		{"comments and empty lines", "# comment\n\n  aGVsbG8=  \n# trailing\n", "hello", false},
+		{"untrusted comment isn't skipped", "untrusted comment: x\naGVsbG8=\n", "", true},
+		{"invalid", "@@@", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeBase64Payload(strings.NewReader(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && string(got) != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyFileSignature(t *testing.T) {
+	pub1, _, _ := ed25519.GenerateKey(nil)
+	pub2, priv2, _ := ed25519.GenerateKey(nil)
+
+	path := filepath.Join(t.TempDir(), "app")
+	data := []byte("binary content")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv2, data))
+	otherSig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv2, []byte("other content")))
+
+	tests := []struct {
+		name    string
+		sig     string
+		keys    []ed25519.PublicKey
+		want    int
+		wantErr error
+	}{
+		{"second key matches", sig, []ed25519.PublicKey{pub1, pub2}, 1, nil},
+		{"no key matches", sig, []ed25519.PublicKey{pub1}, -1, errInvalidSignature},
+		{"signature of other file", otherSig, []ed25519.PublicKey{pub2}, -1, errInvalidSignature},
+		{"truncated signature", sig[:20], []ed25519.PublicKey{pub2}, -1, errors.New("invalid signature file")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifyFileSignature(path, strings.NewReader(tt.sig), tt.keys)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && (err == nil || !strings.Contains(err.Error(), tt.wantErr.Error())) {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Fatalf("got key index %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	data := []byte("binary content")
+
+	srv := httptest.NewServer(serveFiles(map[string]string{
+		"/1.2.0/app_Linux_x86_64.sig": base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data)),
+		"/1.3.0/app_Linux_x86_64.sig": base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte("tampered"))),
+	}))
+	defer srv.Close()
+
+	u := newTestAction(t, srv, &config{
+		SignatureMask: "{{.URL}}/{{.Version}}/{{.Name}}_{{.OS}}_{{.Arch}}.sig",
+		PublicKeys:    []string{base64.StdEncoding.EncodeToString(pub)},
+	})
+	u.fDownloadPath = filepath.Join(t.TempDir(), "app")
+	if err := os.WriteFile(u.fDownloadPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := u.verifySignature("1.2.0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := u.verifySignature("1.3.0"); !errors.Is(err, errInvalidSignature) {
+		t.Fatalf("expected %v, got %v", errInvalidSignature, err)
+	}
+	if err := u.verifySignature("1.4.0"); err == nil {
+		t.Fatal("expected error for missing signature")
+	}
+}
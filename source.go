@@ -0,0 +1,222 @@
+package plasmactlupdate
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/launchrctl/keyring"
+)
+
+const (
+	sourceHTTP   = "http"
+	sourceGitHub = "github"
+	sourceGitLab = "gitlab"
+	sourceOCI    = "oci"
+	sourceS3     = "s3"
+)
+
+// releaseSource is a backend resolving release versions and binaries.
+type releaseSource interface {
+	// init prepares the source before requests, e.g. resolves credentials.
+	init() error
+	// baseURL returns a value of URL template variable.
+	baseURL() string
+	// latestVersion returns the version to install by default.
+	latestVersion() (string, error)
+	// artifactURL returns a download URL of the binary for the version and current platform.
+	artifactURL(version string) (string, error)
+	// prepareRequest sets authentication and headers of the request.
+	prepareRequest(req *http.Request)
+}
+
+// newSource creates a release source from the config.
+func (u *updateAction) newSource() (releaseSource, error) {
+	switch u.cfg.Source {
+	case "", sourceHTTP:
+		return &httpSource{u: u}, nil
+	case sourceGitHub:
+		return newGitHubSource(u), nil
+	case sourceGitLab:
+		return newGitLabSource(u), nil
+	case sourceOCI:
+		return newOCISource(u), nil
+	case sourceS3:
+		return newS3Source(u), nil
+	default:
+		return nil, fmt.Errorf("unsupported update source %q", u.cfg.Source)
+	}
+}
+
+// assetSource is a source serving release files by asset name through API,
+// so the download URLs don't end with the file names.
+type assetSource interface {
+	// artifactName returns a file name of the binary for the version.
+	artifactName(version string) (string, error)
+	// assetURL returns a download URL of the release file with the name, e.g. a checksum file.
+	assetURL(version, name string) (string, error)
+}
+
+// releaseFileURL returns a download URL of the release file formatted from the mask.
+// For asset sources the file is resolved by the name the mask ends with.
+func (u *updateAction) releaseFileURL(mask, version string) (string, error) {
+	fileURL, err := formatURL(mask, u.newTemplateVars(version))
+	if err != nil {
+		return "", err
+	}
+
+	as, ok := u.source.(assetSource)
+	if !ok {
+		return fileURL, nil
+	}
+	name, err := urlFileName(fileURL)
+	if err != nil {
+		return "", err
+	}
+	return as.assetURL(version, name)
+}
+
+// artifactName returns a file name of the downloaded binary, e.g. to find it in a checksum file.
+func (u *updateAction) artifactName(version string) (string, error) {
+	if as, ok := u.source.(assetSource); ok {
+		return as.artifactName(version)
+	}
+	return urlFileName(u.binURL)
+}
+
+// urlFileName returns the unescaped last path segment of the URL.
+func urlFileName(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return path.Base(parsed.Path), nil
+}
+
+// failoverSource is a source able to switch to another mirror on failure.
+type failoverSource interface {
+	// nextMirror switches to the next healthy mirror, returns false if none is left.
+	nextMirror() bool
+}
+
+// authNegotiator is a source able to negotiate auth on an unauthorized response.
+type authNegotiator interface {
+	// negotiateAuth prepares auth from the response challenge, the request is repeated after.
+	negotiateAuth(resp *http.Response) error
+}
+
+// httpSource is a generic HTTP repository addressed with URL templates.
+// Mirrors are used in order, the next one is selected when the current fails.
+type httpSource struct {
+	u       *updateAction
+	mirrors []string
+	current int
+	input   keyring.CredentialsItem
+}
+
+func (s *httpSource) init() error {
+	s.mirrors = s.u.cfg.mirrorURLs()
+	// Keep credentials submitted by user to reuse them for every mirror.
+	s.input = s.u.credentials
+
+	var err error
+	for s.current = 0; s.current < len(s.mirrors); s.current++ {
+		if err = s.selectMirror(); err == nil {
+			return nil
+		}
+		s.u.Term().Warning().Printfln("Mirror %s is unavailable: %s", s.mirrors[s.current], err)
+	}
+
+	return fmt.Errorf("all repository mirrors are unavailable: %w", err)
+}
+
+// selectMirror probes the current mirror if there are others to fail over to and prepares its credentials.
+// Unless auth is configured explicitly, it's negotiated on the first unauthorized response.
+func (s *httpSource) selectMirror() error {
+	u := s.u
+	// Set URL for credentials item.
+	u.credentials = s.input
+	u.credentials.URL = s.mirrors[s.current]
+	u.bearerToken = ""
+	u.authNegotiated = false
+
+	// The health check only matters for failover, a single mirror is used as is.
+	if len(s.mirrors) > 1 {
+		if err := u.probeMirror(u.credentials.URL); err != nil {
+			return err
+		}
+	}
+
+	// Configured auth is always used.
+	u.requiresAuth = u.cfg.isExplicitAuth()
+	u.Log().Debug("repository auth", "url", u.credentials.URL, "auth_type", u.cfg.Auth.Type)
+	if u.requiresAuth {
+		if err := u.resolveCredentials(); err != nil {
+			return err
+		}
+	}
+
+	if len(s.mirrors) > 1 {
+		u.Term().Printfln("Using mirror %s", u.credentials.URL)
+	}
+	return nil
+}
+
+// probeMirror checks the mirror responds to GET of its base URL.
+// Any response except server errors means the mirror is healthy,
+// e.g. unauthorized one is handled by auth negotiation later.
+func (u *updateAction) probeMirror(mirrorURL string) error {
+	req, err := http.NewRequest(http.MethodGet, mirrorURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	u.Log().Debug("mirror probe response", "url", mirrorURL, "status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *httpSource) nextMirror() bool {
+	for s.current++; s.current < len(s.mirrors); s.current++ {
+		err := s.selectMirror()
+		if err == nil {
+			return true
+		}
+		s.u.Term().Warning().Printfln("Mirror %s is unavailable: %s", s.mirrors[s.current], err)
+	}
+	return false
+}
+
+func (s *httpSource) baseURL() string {
+	return s.u.credentials.URL
+}
+
+func (s *httpSource) latestVersion() (string, error) {
+	return s.u.getStableRelease()
+}
+
+func (s *httpSource) artifactURL(version string) (string, error) {
+	// Format the URL with the determined 'os', 'arch' and 'extension' values.
+	fileURL, err := formatURL(s.u.cfg.BinMask, s.u.newTemplateVars(version))
+	if err != nil {
+		return "", fmt.Errorf("failed to format download URL: %w", err)
+	}
+	return fileURL, nil
+}
+
+func (s *httpSource) prepareRequest(req *http.Request) {
+	s.u.setAuth(req)
+}
+
+func (s *httpSource) negotiateAuth(resp *http.Response) error {
+	return s.u.negotiateAuth(resp)
+}
@@ -0,0 +1,169 @@
+package plasmactlupdate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultGitHubAPIURL   = "https://api.github.com"
+	defaultGitHubAssetTpl = "{{.Name}}_{{.OS}}_{{.Arch}}{{.Ext}}"
+	githubDownloadURLTpl  = "https://github.com/%s/%s/releases/download"
+)
+
+// githubRelease is a release returned by GitHub REST API.
+type githubRelease struct {
+	TagName string        `json:"tag_name"`
+	Assets  []githubAsset `json:"assets"`
+}
+
+// githubAsset is a release asset returned by GitHub REST API.
+type githubAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// githubSource resolves releases with GitHub Releases API.
+// Release files are downloaded by asset name through API, so private repositories are supported.
+type githubSource struct {
+	u        *updateAction
+	apiURL   string
+	token    string
+	releases map[string]*githubRelease
+}
+
+func newGitHubSource(u *updateAction) *githubSource {
+	apiURL := strings.TrimSuffix(u.cfg.GitHub.APIURL, "/")
+	if apiURL == "" {
+		apiURL = defaultGitHubAPIURL
+	}
+	return &githubSource{u: u, apiURL: apiURL, releases: make(map[string]*githubRelease)}
+}
+
+func (s *githubSource) init() error {
+	// The token is optional, public repositories are available without it.
+	ci, ok, err := s.u.lookupCredentials(s.apiURL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.u.Log().Debug("github token not found, proceeding without auth", "url", s.apiURL)
+		return nil
+	}
+
+	s.token = ci.Password
+	return nil
+}
+
+func (s *githubSource) baseURL() string {
+	return fmt.Sprintf(githubDownloadURLTpl, s.u.cfg.GitHub.Owner, s.u.cfg.GitHub.Repo)
+}
+
+func (s *githubSource) latestVersion() (string, error) {
+	r, err := s.getRelease("latest")
+	if err != nil {
+		return "", err
+	}
+
+	s.u.Term().Printfln("Latest release: %s", r.TagName)
+	return r.TagName, nil
+}
+
+func (s *githubSource) artifactURL(version string) (string, error) {
+	a, err := s.binaryAsset(version)
+	if err != nil {
+		return "", err
+	}
+	return a.URL, nil
+}
+
+func (s *githubSource) artifactName(version string) (string, error) {
+	a, err := s.binaryAsset(version)
+	if err != nil {
+		return "", err
+	}
+	return a.Name, nil
+}
+
+func (s *githubSource) assetURL(version, name string) (string, error) {
+	a, err := s.findAsset(version, name)
+	if err != nil {
+		return "", err
+	}
+	return a.URL, nil
+}
+
+// binaryAsset returns the asset of the binary for the version and current platform.
+func (s *githubSource) binaryAsset(version string) (*githubAsset, error) {
+	tpl := s.u.cfg.GitHub.AssetMask
+	if tpl == "" {
+		tpl = defaultGitHubAssetTpl
+	}
+	name, err := formatTemplate(tpl, s.u.newTemplateVars(version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format asset name: %w", err)
+	}
+
+	return s.findAsset(version, name)
+}
+
+// findAsset returns the asset of the release by name.
+func (s *githubSource) findAsset(version, name string) (*githubAsset, error) {
+	r, ok := s.releases[version]
+	if !ok {
+		var err error
+		r, err = s.getRelease("tags/" + url.PathEscape(version))
+		if err != nil {
+			return nil, err
+		}
+		s.releases[version] = r
+	}
+
+	for i := range r.Assets {
+		if strings.EqualFold(r.Assets[i].Name, name) {
+			return &r.Assets[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("release %s doesn't have asset %s", r.TagName, name)
+}
+
+func (s *githubSource) prepareRequest(req *http.Request) {
+	// The token is sent only to API, not to hosts assets are redirected to.
+	if !strings.HasPrefix(req.URL.String(), s.apiURL+"/") {
+		return
+	}
+
+	// Assets are downloaded through API to support private repositories.
+	if strings.Contains(req.URL.Path, "/releases/assets/") {
+		req.Header.Set("Accept", "application/octet-stream")
+	} else {
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+}
+
+// getRelease requests a release by API path, e.g. "latest" or "tags/v1.0.0".
+func (s *githubSource) getRelease(p string) (*githubRelease, error) {
+	cfg := s.u.cfg.GitHub
+	releaseURL := fmt.Sprintf("%s/repos/%s/%s/releases/%s", s.apiURL, url.PathEscape(cfg.Owner), url.PathEscape(cfg.Repo), p)
+
+	resp, err := s.u.sendRequest(releaseURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	r := &githubRelease{}
+	if err = json.NewDecoder(resp.Body).Decode(r); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub release: %w", err)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,248 @@
+package plasmactlupdate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/launchrctl/keyring"
+)
+
+// fakeGitHub is a fake GitHub Releases API serving a single repository.
+type fakeGitHub struct {
+	srv    *httptest.Server
+	token  string
+	assets map[string]string
+
+	mu   sync.Mutex
+	auth map[string]string
+}
+
+func newFakeGitHub(t *testing.T, token string) *fakeGitHub {
+	t.Helper()
+	f := &fakeGitHub{
+		token: token,
+		assets: map[string]string{
+			"app_Linux_x86_64":     "binary v1.2.0",
+			"app_Darwin_arm64":     "darwin binary v1.2.0",
+			"checksums.txt":        "",
+			"app_Linux_x86_64.sig": "signature",
+		},
+		auth: make(map[string]string),
+	}
+	sum := sha256.Sum256([]byte(f.assets["app_Linux_x86_64"]))
+	f.assets["checksums.txt"] = hex.EncodeToString(sum[:]) + "  app_Linux_x86_64\n"
+
+	f.srv = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeGitHub) release(tag string) githubRelease {
+	r := githubRelease{TagName: tag}
+	for name := range f.assets {
+		r.Assets = append(r.Assets, githubAsset{Name: name, URL: f.srv.URL + "/api/repos/o/r/releases/assets/" + name})
+	}
+	return r
+}
+
+func (f *fakeGitHub) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.auth[r.URL.Path] = r.Header.Get("Authorization")
+	f.mu.Unlock()
+
+	if f.token != "" && strings.HasPrefix(r.URL.Path, "/api/") && r.Header.Get("Authorization") != "Bearer "+f.token {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/api/repos/o/r/releases/latest":
+		_ = json.NewEncoder(w).Encode(f.release("v1.2.0"))
+	case r.URL.Path == "/api/repos/o/r/releases/tags/v1.1.0", r.URL.Path == "/api/repos/o/r/releases/tags/v1.2.0":
+		_ = json.NewEncoder(w).Encode(f.release(strings.TrimPrefix(r.URL.Path, "/api/repos/o/r/releases/tags/")))
+	case strings.HasPrefix(r.URL.Path, "/api/repos/o/r/releases/assets/"):
+		if r.Header.Get("Accept") != "application/octet-stream" {
+			http.Error(w, "asset metadata", http.StatusBadRequest)
+			return
+		}
+		data, ok := f.assets[strings.TrimPrefix(r.URL.Path, "/api/repos/o/r/releases/assets/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(data))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeGitHub) authOf(path string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.auth[path]
+}
+
+func newGitHubTestAction(t *testing.T, f *fakeGitHub) *updateAction {
+	t.Helper()
+	t.Setenv("TEST_GITHUB_TOKEN", f.token)
+	u := newTestAction(t, f.srv, &config{
+		Source:       sourceGitHub,
+		GitHub:       githubConfig{Owner: "o", Repo: "r", APIURL: f.srv.URL + "/api"},
+		Auth:         authConfig{TokenEnv: "TEST_GITHUB_TOKEN"},
+		ChecksumMask: "{{.URL}}/{{.Version}}/checksums.txt",
+	})
+	return u
+}
+
+func TestGitHubSourceLatestVersion(t *testing.T) {
+	f := newFakeGitHub(t, "")
+	u := newGitHubTestAction(t, f)
+
+	v, err := u.source.latestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "v1.2.0" {
+		t.Fatalf("latest version %q, want v1.2.0", v)
+	}
+}
+
+func TestGitHubSourceAssetSelection(t *testing.T) {
+	f := newFakeGitHub(t, "")
+
+	tests := []struct {
+		name      string
+		version   string
+		os, arch  string
+		assetMask string
+		want      string
+		wantErr   bool
+	}{
+		{"default mask", "v1.2.0", "Linux", "x86_64", "", "app_Linux_x86_64", false},
+		{"other platform", "v1.1.0", "Darwin", "arm64", "", "app_Darwin_arm64", false},
+		{"case-insensitive mask", "v1.2.0", "Linux", "x86_64", "APP_{{.OS}}_{{.Arch}}", "app_Linux_x86_64", false},
+		{"missing asset", "v1.2.0", "Windows", "x86_64", "", "", true},
+		{"missing tag", "v0.1.0", "Linux", "x86_64", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newGitHubTestAction(t, f)
+			u.os, u.arch = tt.os, tt.arch
+			u.cfg.GitHub.AssetMask = tt.assetMask
+
+			assetURL, err := u.source.artifactURL(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if want := f.srv.URL + "/api/repos/o/r/releases/assets/" + tt.want; assetURL != want {
+				t.Fatalf("asset URL %q, want %q", assetURL, want)
+			}
+			name, err := u.artifactName(tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != tt.want {
+				t.Fatalf("artifact name %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubSourceDownloadAndChecksum(t *testing.T) {
+	f := newFakeGitHub(t, "secret")
+	u := newGitHubTestAction(t, f)
+	u.fDownloadPath = filepath.Join(t.TempDir(), "app")
+
+	if err := u.downloadFile("v1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(u.fDownloadPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != f.assets["app_Linux_x86_64"] {
+		t.Fatalf("downloaded %q", data)
+	}
+
+	// The checksum file is a private asset found by name, not by the asset ID of the binary.
+	name, err := u.artifactName("v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = u.verifyChecksum("v1.2.0", name); err != nil {
+		t.Fatalf("unexpected checksum error: %v", err)
+	}
+	if got := f.authOf("/api/repos/o/r/releases/assets/checksums.txt"); got != "Bearer secret" {
+		t.Fatalf("checksum asset requested with auth %q", got)
+	}
+}
+
+func TestGitHubSourceTokenScope(t *testing.T) {
+	f := newFakeGitHub(t, "secret")
+	u := newGitHubTestAction(t, f)
+
+	tests := []struct {
+		url  string
+		auth bool
+	}{
+		{f.srv.URL + "/api/repos/o/r/releases/latest", true},
+		{f.srv.URL + "/api/repos/o/r/releases/assets/1", true},
+		{f.srv.URL + "/api-other/file", false},
+		{f.srv.URL + "/download/file", false},
+		{"https://objects.githubusercontent.com/file", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u.source.prepareRequest(req)
+			if got := req.Header.Get("Authorization") != ""; got != tt.auth {
+				t.Fatalf("authorization set %v, want %v", got, tt.auth)
+			}
+		})
+	}
+}
+
+func TestGitHubSourceTokenFromOption(t *testing.T) {
+	f := newFakeGitHub(t, "secret")
+	t.Setenv("TEST_GITHUB_TOKEN", "stale")
+
+	// The token from --token option wins over the environment.
+	u := &updateAction{
+		cfg: &config{
+			Source: sourceGitHub,
+			GitHub: githubConfig{Owner: "o", Repo: "r", APIURL: f.srv.URL + "/api"},
+			Auth:   authConfig{TokenEnv: "TEST_GITHUB_TOKEN"},
+		},
+		credentials:    keyring.CredentialsItem{Password: "secret"},
+		appName:        "app",
+		os:             "Linux",
+		arch:           "x86_64",
+		client:         f.srv.Client(),
+		nonInteractive: true,
+	}
+	u.source = newGitHubSource(u)
+	if err := u.source.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := u.source.latestVersion(); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.authOf("/api/repos/o/r/releases/latest"); got != "Bearer secret" {
+		t.Fatalf("latest release requested with auth %q", got)
+	}
+}
@@ -0,0 +1,161 @@
+package plasmactlupdate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	gitlabTokenPrivate = "private"
+	gitlabTokenJob     = "job"
+
+	gitlabHeaderPrivate = "PRIVATE-TOKEN"
+	gitlabHeaderJob     = "JOB-TOKEN"
+
+	gitlabVersionsReleases = "releases"
+	gitlabVersionsPackages = "packages"
+
+	defaultGitLabFileTpl = "{{.Name}}_{{.OS}}_{{.Arch}}{{.Ext}}"
+)
+
+// gitlabRelease is a release returned by GitLab REST API.
+type gitlabRelease struct {
+	TagName string `json:"tag_name"`
+}
+
+// gitlabPackage is a package returned by GitLab REST API.
+type gitlabPackage struct {
+	Version string `json:"version"`
+}
+
+// gitlabSource resolves releases from GitLab generic package registry.
+type gitlabSource struct {
+	u      *updateAction
+	apiURL string
+	token  string
+}
+
+func newGitLabSource(u *updateAction) *gitlabSource {
+	base := strings.TrimSuffix(u.cfg.GitLab.URL, "/")
+	return &gitlabSource{
+		u:      u,
+		apiURL: fmt.Sprintf("%s/api/v4/projects/%s", base, url.PathEscape(u.cfg.GitLab.Project)),
+	}
+}
+
+func (s *gitlabSource) init() error {
+	// The token is optional, public projects are available without it.
+	ci, ok, err := s.u.lookupCredentials(s.u.cfg.GitLab.URL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.u.Log().Debug("gitlab token not found, proceeding without auth", "url", s.u.cfg.GitLab.URL)
+		return nil
+	}
+
+	s.token = ci.Password
+	return nil
+}
+
+func (s *gitlabSource) baseURL() string {
+	return fmt.Sprintf("%s/packages/generic/%s", s.apiURL, url.PathEscape(s.packageName()))
+}
+
+func (s *gitlabSource) latestVersion() (string, error) {
+	var v string
+	var err error
+	if s.u.cfg.GitLab.VersionsFrom == gitlabVersionsPackages {
+		v, err = s.latestPackage()
+	} else {
+		v, err = s.latestRelease()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	s.u.Term().Printfln("Latest release: %s", v)
+	return v, nil
+}
+
+func (s *gitlabSource) artifactURL(version string) (string, error) {
+	tpl := s.u.cfg.GitLab.FileMask
+	if tpl == "" {
+		tpl = defaultGitLabFileTpl
+	}
+	name, err := formatTemplate(tpl, s.u.newTemplateVars(version))
+	if err != nil {
+		return "", fmt.Errorf("failed to format package file name: %w", err)
+	}
+
+	return fmt.Sprintf("%s/%s/%s", s.baseURL(), url.PathEscape(version), url.PathEscape(name)), nil
+}
+
+func (s *gitlabSource) prepareRequest(req *http.Request) {
+	if s.token == "" || !strings.HasPrefix(req.URL.String(), s.apiURL+"/") {
+		return
+	}
+
+	if s.u.cfg.GitLab.TokenType == gitlabTokenJob {
+		req.Header.Set(gitlabHeaderJob, s.token)
+	} else {
+		req.Header.Set(gitlabHeaderPrivate, s.token)
+	}
+}
+
+func (s *gitlabSource) packageName() string {
+	if s.u.cfg.GitLab.PackageName != "" {
+		return s.u.cfg.GitLab.PackageName
+	}
+	return s.u.appName
+}
+
+// latestRelease returns a tag of the most recent project release.
+func (s *gitlabSource) latestRelease() (string, error) {
+	var releases []gitlabRelease
+	if err := s.getJSON(s.apiURL+"/releases?order_by=released_at&sort=desc&per_page=1", &releases); err != nil {
+		return "", err
+	}
+	if len(releases) == 0 {
+		return "", fmt.Errorf("project %s doesn't have releases", s.u.cfg.GitLab.Project)
+	}
+
+	return releases[0].TagName, nil
+}
+
+// latestPackage returns a version of the most recent generic package.
+func (s *gitlabSource) latestPackage() (string, error) {
+	q := url.Values{}
+	q.Set("package_type", "generic")
+	q.Set("package_name", s.packageName())
+	q.Set("order_by", "created_at")
+	q.Set("sort", "desc")
+	q.Set("per_page", "1")
+
+	var packages []gitlabPackage
+	if err := s.getJSON(s.apiURL+"/packages?"+q.Encode(), &packages); err != nil {
+		return "", err
+	}
+	if len(packages) == 0 {
+		return "", fmt.Errorf("project %s doesn't have package %s", s.u.cfg.GitLab.Project, s.packageName())
+	}
+
+	return packages[0].Version, nil
+}
+
+func (s *gitlabSource) getJSON(reqURL string, v any) error {
+	resp, err := s.u.sendRequest(reqURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse GitLab response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package plasmactlupdate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// headerRecorder serves the body and records request headers.
+type headerRecorder struct {
+	body string
+
+	mu      sync.Mutex
+	headers http.Header
+}
+
+func (h *headerRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.record(r)
+	_, _ = w.Write([]byte(h.body))
+}
+
+func (h *headerRecorder) record(r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.headers = r.Header.Clone()
+}
+
+func (h *headerRecorder) header(name string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.headers.Get(name)
+}
+
+func TestGitLabSourceRedirectDropsToken(t *testing.T) {
+	for _, tokenType := range []string{gitlabTokenPrivate, gitlabTokenJob} {
+		t.Run(tokenType, func(t *testing.T) {
+			// Object storage serving presigned URLs.
+			storage := &headerRecorder{body: "binary v1.2.0"}
+			storageSrv := httptest.NewServer(storage)
+			defer storageSrv.Close()
+
+			registry := &headerRecorder{}
+			gitlab := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/packages/generic/app/1.2.0/app_Linux_x86_64") {
+					http.NotFound(w, r)
+					return
+				}
+				registry.record(r)
+				http.Redirect(w, r, storageSrv.URL+"/bucket/app?X-Amz-Signature=abc", http.StatusFound)
+			}))
+			defer gitlab.Close()
+
+			t.Setenv("TEST_GITLAB_TOKEN", "secret")
+			u := newTestAction(t, gitlab, &config{
+				Source: sourceGitLab,
+				GitLab: gitlabConfig{URL: gitlab.URL, Project: "group/project", TokenType: tokenType},
+				Auth:   authConfig{TokenEnv: "TEST_GITLAB_TOKEN"},
+			})
+			var err error
+			if u.client, err = u.newHTTPClient(); err != nil {
+				t.Fatal(err)
+			}
+			u.fDownloadPath = filepath.Join(t.TempDir(), "app")
+
+			if err = u.downloadFile("1.2.0"); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(u.fDownloadPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != storage.body {
+				t.Fatalf("downloaded %q", data)
+			}
+
+			for _, h := range []string{gitlabHeaderPrivate, gitlabHeaderJob} {
+				if got := storage.header(h); got != "" {
+					t.Errorf("%s header %q is sent to object storage", h, got)
+				}
+			}
+			if registry.header(gitlabHeaderPrivate)+registry.header(gitlabHeaderJob) != "secret" {
+				t.Errorf("token isn't sent to the registry")
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package plasmactlupdate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+
+	"github.com/launchrctl/keyring"
+)
+
+const (
+	defaultOCITag     = "latest"
+	defaultOCIFileTpl = "{{.Name}}_{{.OS}}_{{.Arch}}{{.Ext}}"
+
+	ociAnnotationTitle   = "org.opencontainers.image.title"
+	ociAnnotationVersion = "org.opencontainers.image.version"
+	ociDigestSHA256      = "sha256:"
+)
+
+// ociManifestAccept lists supported manifest media types.
+var ociManifestAccept = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// ociDescriptor describes content in a registry.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Platform    *ociPlatform      `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ociPlatform describes a platform of an index entry.
+type ociPlatform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+}
+
+// ociManifest is either an image index or an image manifest.
+type ociManifest struct {
+	MediaType   string            `json:"mediaType"`
+	Manifests   []ociDescriptor   `json:"manifests,omitempty"`
+	Layers      []ociDescriptor   `json:"layers,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ociSource resolves releases published as OCI artifacts.
+type ociSource struct {
+	u        *updateAction
+	registry string
+	creds    keyring.CredentialsItem
+	token    string
+	basic    bool
+	resolved map[string]*ociManifest
+}
+
+func newOCISource(u *updateAction) *ociSource {
+	return &ociSource{
+		u:        u,
+		registry: strings.TrimSuffix(u.cfg.OCI.Registry, "/"),
+		resolved: make(map[string]*ociManifest),
+	}
+}
+
+func (s *ociSource) init() error {
+	// Credentials are optional, public repositories are available anonymously.
+	ci, ok, err := s.u.lookupCredentials(s.registry)
+	if err != nil {
+		return err
+	}
+	if ok {
+		s.creds = ci
+	}
+
+	// Probe the registry API to negotiate auth.
+	resp, err := s.u.client.Get(s.registry + "/v2/")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	u := s.u
+	u.Log().Debug("registry auth probe", "url", s.registry, "status_code", resp.StatusCode)
+	if resp.StatusCode != http.StatusUnauthorized {
+		return nil
+	}
+
+	ch := parseAuthChallenge(resp.Header.Get("WWW-Authenticate"))
+	switch ch.scheme {
+	case "basic":
+		s.basic = true
+		return nil
+	case "bearer":
+		return s.fetchToken(resp.Request.URL, ch)
+	default:
+		return fmt.Errorf("unsupported registry auth scheme %q", ch.scheme)
+	}
+}
+
+// fetchToken requests a pull token from the registry token service.
+func (s *ociSource) fetchToken(origin *url.URL, ch authChallenge) error {
+	scope := fmt.Sprintf("repository:%s:pull", s.u.cfg.OCI.Repository)
+	token, err := s.u.fetchBearerToken(origin, ch, scope, s.creds.Username, s.creds.Password)
+	if err != nil {
+		return fmt.Errorf("failed to get registry token: %w", err)
+	}
+
+	s.token = token
+	return nil
+}
+
+func (s *ociSource) baseURL() string {
+	return s.registry
+}
+
+func (s *ociSource) latestVersion() (string, error) {
+	tag := s.u.activeChannel
+	if tag == "" {
+		tag = s.u.cfg.OCI.Tag
+	}
+	if tag == "" {
+		tag = defaultOCITag
+	}
+
+	m, version, err := s.resolve(tag)
+	if err != nil {
+		return "", err
+	}
+	// A tag like "latest" can't be compared with the current version.
+	if version == "" {
+		return "", fmt.Errorf("manifest of tag %s doesn't have %s annotation with the release version", tag, ociAnnotationVersion)
+	}
+	s.resolved[version] = m
+
+	s.u.Term().Printfln("Latest release: %s", version)
+	return version, nil
+}
+
+func (s *ociSource) artifactURL(version string) (string, error) {
+	m, ok := s.resolved[version]
+	if !ok {
+		var err error
+		m, _, err = s.resolve(version)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	layer, err := s.findLayer(m, version)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(layer.Digest, ociDigestSHA256) {
+		return "", fmt.Errorf("unsupported digest algorithm of %s", layer.Digest)
+	}
+
+	blobURL := fmt.Sprintf("%s/v2/%s/blobs/%s", s.registry, s.u.cfg.OCI.Repository, layer.Digest)
+	// The blob is verified with its digest after download.
+	s.u.artifact = &manifestArtifact{
+		URL:    blobURL,
+		SHA256: strings.TrimPrefix(layer.Digest, ociDigestSHA256),
+		Size:   layer.Size,
+	}
+
+	return blobURL, nil
+}
+
+func (s *ociSource) prepareRequest(req *http.Request) {
+	if !strings.HasPrefix(req.URL.String(), s.registry+"/") {
+		return
+	}
+
+	if strings.Contains(req.URL.Path, "/manifests/") {
+		req.Header.Set("Accept", ociManifestAccept)
+	}
+	switch {
+	case s.token != "":
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	case s.basic && s.creds.Username != "":
+		req.SetBasicAuth(s.creds.Username, s.creds.Password)
+	}
+}
+
+// resolve returns a platform manifest for the reference and a version from its annotation.
+// The version is empty if the manifest isn't annotated.
+func (s *ociSource) resolve(ref string) (*ociManifest, string, error) {
+	m, err := s.getManifest(ref)
+	if err != nil {
+		return nil, "", err
+	}
+
+	version := m.Annotations[ociAnnotationVersion]
+	if len(m.Manifests) > 0 {
+		d, err := findPlatform(m.Manifests)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", ref, err)
+		}
+		m, err = s.getManifest(d.Digest)
+		if err != nil {
+			return nil, "", err
+		}
+		if v := m.Annotations[ociAnnotationVersion]; v != "" {
+			version = v
+		}
+	}
+
+	return m, version, nil
+}
+
+func (s *ociSource) getManifest(ref string) (*ociManifest, error) {
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", s.registry, s.u.cfg.OCI.Repository, url.PathEscape(ref))
+	resp, err := s.u.sendRequest(manifestURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	m := &ociManifest{}
+	if err = json.NewDecoder(resp.Body).Decode(m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", ref, err)
+	}
+
+	return m, nil
+}
+
+// findLayer selects the binary layer by its title annotation.
+func (s *ociSource) findLayer(m *ociManifest, version string) (*ociDescriptor, error) {
+	tpl := s.u.cfg.OCI.FileMask
+	if tpl == "" && len(m.Layers) == 1 {
+		return &m.Layers[0], nil
+	}
+	if tpl == "" {
+		tpl = defaultOCIFileTpl
+	}
+
+	name, err := formatTemplate(tpl, s.u.newTemplateVars(version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format layer file name: %w", err)
+	}
+	for i := range m.Layers {
+		if strings.EqualFold(m.Layers[i].Annotations[ociAnnotationTitle], name) {
+			return &m.Layers[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("manifest of %s doesn't have layer %s", version, name)
+}
+
+// findPlatform selects an index entry for the current platform.
+func findPlatform(manifests []ociDescriptor) (*ociDescriptor, error) {
+	for i := range manifests {
+		p := manifests[i].Platform
+		if p != nil && p.OS == runtime.GOOS && p.Architecture == runtime.GOARCH {
+			return &manifests[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no manifest for platform %s/%s", runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,177 @@
+package plasmactlupdate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const testOCIToken = "pull-token"
+
+// fakeRegistry is a registry stub serving an index, a platform manifest and a binary blob
+// behind token auth.
+type fakeRegistry struct {
+	srv        *httptest.Server
+	blob       string
+	version    string
+	tokenScope string
+}
+
+func newFakeRegistry(t *testing.T, version string) *fakeRegistry {
+	t.Helper()
+	f := &fakeRegistry{blob: "oci binary", version: version}
+	f.srv = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeRegistry) blobDigest() string {
+	sum := sha256.Sum256([]byte(f.blob))
+	return ociDigestSHA256 + hex.EncodeToString(sum[:])
+}
+
+func (f *fakeRegistry) manifestDigest() string {
+	return ociDigestSHA256 + strings.Repeat("a", 64)
+}
+
+func (f *fakeRegistry) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		f.tokenScope = r.URL.Query().Get("scope")
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": testOCIToken})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+testOCIToken {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry"`, f.srv.URL))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v2/":
+		w.WriteHeader(http.StatusOK)
+	case "/v2/my-org/app/manifests/latest", "/v2/my-org/app/manifests/1.2.0":
+		_ = json.NewEncoder(w).Encode(ociManifest{
+			MediaType: "application/vnd.oci.image.index.v1+json",
+			Manifests: []ociDescriptor{
+				{Digest: ociDigestSHA256 + strings.Repeat("b", 64), Platform: &ociPlatform{OS: "plan9", Architecture: "mips"}},
+				{Digest: f.manifestDigest(), Platform: &ociPlatform{OS: runtime.GOOS, Architecture: runtime.GOARCH}},
+			},
+		})
+	case "/v2/my-org/app/manifests/" + f.manifestDigest():
+		m := ociManifest{
+			MediaType: "application/vnd.oci.image.manifest.v1+json",
+			Layers: []ociDescriptor{
+				{Digest: ociDigestSHA256 + strings.Repeat("c", 64), Annotations: map[string]string{ociAnnotationTitle: "README.md"}},
+				{Digest: f.blobDigest(), Size: int64(len(f.blob)), Annotations: map[string]string{ociAnnotationTitle: "app_Linux_x86_64"}},
+			},
+		}
+		if f.version != "" {
+			m.Annotations = map[string]string{ociAnnotationVersion: f.version}
+		}
+		_ = json.NewEncoder(w).Encode(m)
+	case "/v2/my-org/app/blobs/" + f.blobDigest():
+		_, _ = w.Write([]byte(f.blob))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newOCITestAction(t *testing.T, f *fakeRegistry) *updateAction {
+	t.Helper()
+	return newTestAction(t, f.srv, &config{
+		Source: sourceOCI,
+		OCI:    ociConfig{Registry: f.srv.URL, Repository: "my-org/app"},
+	})
+}
+
+func TestOCISourceResolveAndDownload(t *testing.T) {
+	f := newFakeRegistry(t, "1.2.0")
+	u := newOCITestAction(t, f)
+
+	if f.tokenScope != "repository:my-org/app:pull" {
+		t.Fatalf("token requested with scope %q", f.tokenScope)
+	}
+
+	version, err := u.source.latestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.2.0" {
+		t.Fatalf("version %q, want 1.2.0", version)
+	}
+
+	blobURL, err := u.source.artifactURL(version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := f.srv.URL + "/v2/my-org/app/blobs/" + f.blobDigest(); blobURL != want {
+		t.Fatalf("blob URL %q, want %q", blobURL, want)
+	}
+
+	u.fDownloadPath = filepath.Join(t.TempDir(), "app")
+	if err = u.downloadFile(version); err != nil {
+		t.Fatal(err)
+	}
+	if err = u.verifyArtifact(); err != nil {
+		t.Fatalf("blob digest verification failed: %v", err)
+	}
+	data, err := os.ReadFile(u.fDownloadPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != f.blob {
+		t.Fatalf("downloaded %q", data)
+	}
+}
+
+func TestOCISourceTargetVersion(t *testing.T) {
+	// An explicit version is used as a tag and doesn't require the annotation.
+	f := newFakeRegistry(t, "")
+	u := newOCITestAction(t, f)
+
+	if _, err := u.source.artifactURL("1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	if u.artifact == nil || "sha256:"+u.artifact.SHA256 != f.blobDigest() {
+		t.Fatalf("unexpected artifact %+v", u.artifact)
+	}
+}
+
+func TestOCISourceRequiresVersionAnnotation(t *testing.T) {
+	f := newFakeRegistry(t, "")
+	u := newOCITestAction(t, f)
+
+	_, err := u.source.latestVersion()
+	if err == nil || !strings.Contains(err.Error(), ociAnnotationVersion) {
+		t.Fatalf("expected missing annotation error, got %v", err)
+	}
+}
+
+func TestOCISourceTokenScope(t *testing.T) {
+	f := newFakeRegistry(t, "1.2.0")
+	u := newOCITestAction(t, f)
+
+	for url, auth := range map[string]bool{
+		f.srv.URL + "/v2/my-org/app/manifests/latest": true,
+		f.srv.URL + "0/v2/":                           false,
+		"https://cdn.example.com/blob":                false,
+	} {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u.source.prepareRequest(req)
+		if got := req.Header.Get("Authorization") != ""; got != auth {
+			t.Fatalf("%s: authorization set %v, want %v", url, got, auth)
+		}
+	}
+}
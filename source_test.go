@@ -0,0 +1,81 @@
+package plasmactlupdate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHTTPSourceSkipsUnavailableMirror(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	// Unauthorized mirror is reachable, auth is negotiated later.
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer up.Close()
+
+	u := newTestAction(t, up, &config{
+		RepositoryURLs: []mirror{{URL: down.URL}, {URL: up.URL}},
+	})
+	if got := u.source.baseURL(); got != up.URL {
+		t.Fatalf("selected mirror %q, want %q", got, up.URL)
+	}
+}
+
+func TestHTTPSourceAllMirrorsUnavailable(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	u := &updateAction{
+		cfg:            &config{RepositoryURLs: []mirror{{URL: down.URL}, {URL: closed.URL}}},
+		client:         down.Client(),
+		nonInteractive: true,
+	}
+	u.source = &httpSource{u: u}
+	err := u.source.init()
+	if err == nil || !strings.Contains(err.Error(), "all repository mirrors are unavailable") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestHTTPSourceSingleMirrorWithoutProbe(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// A single mirror is used without a request to the repository root.
+	u := newTestAction(t, srv, &config{RepositoryURL: srv.URL})
+	if got := u.source.baseURL(); got != srv.URL {
+		t.Fatalf("selected mirror %q, want %q", got, srv.URL)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("%d requests sent on init, want none", n)
+	}
+}
+
+func TestS3SourceCapabilities(t *testing.T) {
+	var src releaseSource = newS3Source(&updateAction{cfg: &config{
+		Source: sourceS3,
+		S3:     s3Config{Bucket: "releases", Region: "us-east-1"},
+	}})
+
+	// Requests are signed with access keys, negotiated credentials would never be sent.
+	if _, ok := src.(authNegotiator); ok {
+		t.Error("s3 source must not negotiate auth")
+	}
+	if _, ok := src.(failoverSource); ok {
+		t.Error("s3 source must not switch mirrors")
+	}
+}
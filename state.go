@@ -0,0 +1,71 @@
+package plasmactlupdate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/launchrctl/launchr"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	userDataDirTpl = "%s-update"
+	stateFileName  = "state.yaml"
+)
+
+// userState is a per-user state of the updater persisted between runs.
+type userState struct {
+	Channel string `yaml:"channel,omitempty"`
+}
+
+// userDataDir returns a per-user directory of the updater.
+func userDataDir(appName string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf(userDataDirTpl, appName)), nil
+}
+
+// loadState reads the user state. An empty state is returned if it doesn't exist.
+func (u *updateAction) loadState() (*userState, error) {
+	st := &userState{}
+	dir, err := userDataDir(u.appName)
+	if err != nil {
+		return st, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, stateFileName)) //nolint:gosec // Path is built from user config dir.
+	if err != nil {
+		if os.IsNotExist(err) {
+			return st, nil
+		}
+		return st, err
+	}
+
+	if err = yaml.Unmarshal(data, st); err != nil {
+		return st, fmt.Errorf("failed to parse state file: %w", err)
+	}
+
+	return st, nil
+}
+
+// saveState writes the user state.
+func (u *updateAction) saveState(st *userState) error {
+	dir, err := userDataDir(u.appName)
+	if err != nil {
+		return err
+	}
+
+	if err = launchr.EnsurePath(dir); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, stateFileName), data, 0600)
+}
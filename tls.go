@@ -0,0 +1,108 @@
+package plasmactlupdate
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/launchrctl/keyring"
+)
+
+// tlsConfig is a TLS configuration of repository connections.
+type tlsConfig struct {
+	CAFile     string `yaml:"ca_file"`
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+	KeyringURL string `yaml:"keyring_url"`
+	MinVersion string `yaml:"min_version"`
+	Insecure   bool   `yaml:"insecure_skip_verify"`
+}
+
+// tlsVersions maps config values to TLS versions.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func validateTLSConfig(cfg tlsConfig) error {
+	if cfg.MinVersion != "" {
+		if _, ok := tlsVersions[cfg.MinVersion]; !ok {
+			return fmt.Errorf("field 'tls.min_version' must be one of [1.2, 1.3]")
+		}
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return fmt.Errorf("fields 'tls.cert_file' and 'tls.key_file' must be set together")
+	}
+	if cfg.CertFile != "" && cfg.KeyringURL != "" {
+		return fmt.Errorf("fields 'tls.cert_file' and 'tls.keyring_url' can't be used together")
+	}
+	return nil
+}
+
+// newTLSConfig creates TLS configuration for the shared HTTP transport.
+func (u *updateAction) newTLSConfig() (*tls.Config, error) {
+	cfg := u.cfg.TLS
+	tc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if v, ok := tlsVersions[cfg.MinVersion]; ok {
+		tc.MinVersion = v
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(filepath.Clean(cfg.CAFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CAFile)
+		}
+		tc.RootCAs = pool
+	}
+
+	var certPEM, keyPEM []byte
+	switch {
+	case cfg.CertFile != "":
+		var err error
+		if certPEM, err = os.ReadFile(filepath.Clean(cfg.CertFile)); err != nil {
+			return nil, fmt.Errorf("failed to read client certificate: %w", err)
+		}
+		if keyPEM, err = os.ReadFile(filepath.Clean(cfg.KeyFile)); err != nil {
+			return nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+	case cfg.KeyringURL != "":
+		// The certificate and the key are stored as username and password of the keyring item.
+		if u.nonInteractive {
+			return nil, fmt.Errorf("keyring isn't available in non-interactive mode, use 'tls.cert_file' and 'tls.key_file'")
+		}
+		ci, err := u.k.GetForURL(cfg.KeyringURL)
+		if err != nil {
+			if errors.Is(err, keyring.ErrEmptyPass) {
+				return nil, err
+			} else if !errors.Is(err, keyring.ErrNotFound) {
+				return nil, errMalformedKeyring
+			}
+			return nil, fmt.Errorf("client certificate for %s not found in keyring", cfg.KeyringURL)
+		}
+		certPEM, keyPEM = []byte(ci.Username), []byte(ci.Password)
+	}
+	if certPEM != nil {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.Insecure {
+		u.Term().Warning().Println("WARNING: TLS certificate verification is disabled. Connections to the repository are NOT secure!")
+		tc.InsecureSkipVerify = true //nolint:gosec // Explicitly requested in config.
+	}
+
+	return tc, nil
+}
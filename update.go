@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/launchrctl/keyring"
@@ -29,38 +30,80 @@ var archMap = map[string]string{
 }
 
 const (
-	sudoCmd  = "sudo"
-	doasCmd  = "doas"
-	chmodCmd = "chmod"
+	sudoCmd    = "sudo"
+	doasCmd    = "doas"
+	run0Cmd    = "run0"
+	pkexecCmd  = "pkexec"
+	installCmd = "install"
+	mvCmd      = "mv"
+	rmCmd      = "rm"
 )
 
+const (
+	escalationAuto = "auto"
+	escalationNone = "none"
+)
+
+// escalationCmds are privilege escalation commands in order they are looked up.
+var escalationCmds = []string{sudoCmd, doasCmd, run0Cmd, pkexecCmd}
+
 var errNoWritePermission = errors.New("no write permission to binary directory")
 
+// exitCodeUpdateAvailable is returned in check mode when a new version is available.
+const exitCodeUpdateAvailable = 10
+
 type updateAction struct {
 	action.WithLogger
 	action.WithTerm
 	k keyring.Keyring
 
-	cfg           *config
-	targetVersion string
+	cfg            *config
+	targetVersion  string
+	checkOnly      bool
+	allowDowngrade bool
+	channel        string
+	pinnedOverride bool
+	activeChannel  string
+	nonInteractive bool
 
 	// runtime vars.
-	credentials  keyring.CredentialsItem
-	ext          string
-	fName        string
-	fTmpPath     string
-	fPath        string
-	fDir         string
-	sudoCmd      string
-	appName      string
-	os           string
-	arch         string
-	requiresAuth bool
+	credentials    keyring.CredentialsItem
+	ext            string
+	fName          string
+	fTmpDir        string
+	fTmpPath       string
+	fDownloadPath  string
+	fPath          string
+	fDir           string
+	binURL         string
+	manifest       *releaseManifest
+	manifestMirror string
+	artifact       *manifestArtifact
+	escalationCmd  string
+	installDir     string
+	keepVersions   int
+	userInstall    bool
+	execPath       string
+	appName        string
+	os             string
+	arch           string
+	requiresAuth   bool
+	authNegotiated bool
+	bearerToken    string
+	client         *http.Client
+	source         releaseSource
 }
 
 func (u *updateAction) doRun() error {
-	version := launchr.Version()
-	u.Term().Info().Printfln("Starting %s installation...", version.Name)
+	return u.run(launchr.Version())
+}
+
+// run updates the application of the given current version.
+func (u *updateAction) run(version *launchr.AppVersion) error {
+	u.appName = version.Name
+	if !u.checkOnly {
+		u.Term().Info().Printfln("Starting %s installation...", version.Name)
+	}
 	u.Log().Debug("current app info", "name", version.Name, "version", version.Version, "os", version.OS, "arch", version.Arch)
 
 	err := u.initVars()
@@ -69,45 +112,116 @@ func (u *updateAction) doRun() error {
 	}
 
 	var versionToGet string
-	if u.targetVersion != "" {
-		// Get a specific version.
-		versionToGet = u.targetVersion
-	} else {
-		// Get value of Stable Release.
-		versionToGet, err = u.getStableRelease()
-		if err != nil {
-			return err
-		}
+	err = u.withFailover(func() error {
+		versionToGet, err = u.resolveVersion()
+		return err
+	})
+	if err != nil {
+		return err
 	}
 
 	// check if the current version is up to date.
-	if version.Version == versionToGet {
+	change := compareVersions(version.Version, versionToGet)
+	u.Log().Debug("version change", "current", version.Version, "target", versionToGet, "change", change)
+	if u.manifest != nil {
+		if err = u.resolveManifestArtifact(version.Version, versionToGet); err != nil {
+			return err
+		}
+	}
+	if u.checkOnly {
+		return u.reportCheck(version.Version, versionToGet, change)
+	}
+	if change == changeNone {
+		u.rememberChannel()
 		u.Term().Printfln("Current version of %s is up to date.", version.Name)
 		return nil
 	}
 
-	// Download file to the temp folder.
-	if err = u.downloadFile(versionToGet); err != nil {
+	if (change == changeDowngrade || change == changeUnknown) && !u.allowDowngrade {
+		if change == changeUnknown {
+			return fmt.Errorf("can't compare current version %s with %s, use --allow-downgrade to install it anyway", version.Version, versionToGet)
+		}
+		return fmt.Errorf("refusing to downgrade from %s to %s, use --allow-downgrade to install it anyway", version.Version, versionToGet)
+	}
+	u.Term().Printfln("Performing %s from %s to %s", change, version.Version, versionToGet)
+
+	// Download file to the private temp folder.
+	if err = u.prepareTmpDir(); err != nil {
+		return err
+	}
+	err = u.withFailover(func() error {
+		return u.downloadFile(versionToGet)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Verify integrity of the downloaded file.
+	if u.artifact != nil {
+		if err = u.verifyArtifact(); err != nil {
+			return err
+		}
+	}
+	if u.cfg.ChecksumMask != "" {
+		var binName string
+		if binName, err = u.artifactName(versionToGet); err != nil {
+			return err
+		}
+		if err = u.verifyChecksum(versionToGet, binName); err != nil {
+			return err
+		}
+	}
+
+	// Verify the binary was produced by the release pipeline.
+	if len(u.cfg.PublicKeys) > 0 {
+		if err = u.verifySignature(versionToGet); err != nil {
+			return err
+		}
+	}
+
+	// Extract the binary from the release archive.
+	if u.isArchive() {
+		if err = u.extractBinary(versionToGet); err != nil {
+			return err
+		}
+	}
+
+	if err = makeExecutable(u.fTmpPath); err != nil {
 		return err
 	}
 
 	u.Log().Debug("binary path", "path", u.fPath)
 
-	if err = u.installFile(u.fDir); err != nil {
+	if err = u.installWithRollback(versionToGet); err != nil {
 		return err
 	}
+	if u.isVersioned() {
+		u.pruneVersions()
+	}
+	u.rememberChannel()
 
 	// Outro.
 	u.Term().Success().Printfln("%s has been installed successfully.", u.fName)
+	u.warnInstallDirPath()
 	return nil
 }
 
+// reportCheck prints current and available versions without installing anything.
+// Returns an exit error if an upgrade is available.
+func (u *updateAction) reportCheck(current, available string, change versionChange) error {
+	u.Term().Printfln("Current version: %s", current)
+	u.Term().Printfln("Available version: %s (%s)", available, change)
+	if change != changeUpgrade {
+		return nil
+	}
+
+	return launchr.NewExitError(exitCodeUpdateAvailable, fmt.Sprintf("update of %s is available: %s", u.appName, available))
+}
+
 // initVars initialize plugin variables.
 func (u *updateAction) initVars() error {
 	var err error
 
-	u.appName = launchr.Version().Name
-
 	// Get the operating system type.
 	u.os, err = getOS()
 	if err != nil {
@@ -117,6 +231,7 @@ func (u *updateAction) initVars() error {
 
 	// Get the machine architecture.
 	u.arch = getArch()
+	u.ext = binaryExt()
 
 	if u.cfg == nil {
 		return fmt.Errorf("update config is not set, use --config flag or build launchr with predefined config")
@@ -127,24 +242,27 @@ func (u *updateAction) initVars() error {
 		u.Log().Debug("config validation failed", "error", err)
 		return fmt.Errorf("not enough configuration for update. Please ensure your build is with correct tags. See debug for missing info")
 	}
+	// Checked at runtime, the config may be generated on another OS.
+	if u.isVersioned() && runtime.GOOS == "windows" {
+		return fmt.Errorf("install layout %s isn't supported on windows, use %s", installLayoutVersioned, installLayoutInPlace)
+	}
+	u.nonInteractive = u.nonInteractive || u.cfg.Auth.NonInteractive
 
-	// Set URL for credentials item.
-	u.credentials.URL = u.cfg.RepositoryURL
-	authRequired, err := u.checkAuthRequired(u.credentials.URL)
-	if err != nil {
-		u.Log().Debug("failed to check auth requirement, proceeding without auth", "error", err)
-		authRequired = false // Proceed without requiring auth
+	if err = u.resolveChannel(); err != nil {
+		return err
 	}
 
-	u.requiresAuth = authRequired
-	u.Log().Debug("repository auth requirement", "requires_auth", u.requiresAuth)
+	u.client, err = u.newHTTPClient()
+	if err != nil {
+		return err
+	}
 
-	// Only get credentials if auth is required
-	if u.requiresAuth {
-		// Get username and password.
-		if err = u.getCredentials(); err != nil {
-			return err
-		}
+	u.source, err = u.newSource()
+	if err != nil {
+		return err
+	}
+	if err = u.source.init(); err != nil {
+		return err
 	}
 
 	// Prepare binary paths
@@ -154,8 +272,10 @@ func (u *updateAction) initVars() error {
 	}
 
 	u.Log().Debug("initialized environment",
-		"os", u.os, "arch", u.arch, "temp_path", u.fTmpPath, "url", u.credentials.URL,
+		"os", u.os, "arch", u.arch, "url", u.credentials.URL, "source", u.cfg.Source,
 		"base URL", u.cfg.RepositoryURL, "stable release", u.cfg.PinnedRelease, "bin_mask", u.cfg.BinMask,
+		"proxy_url", redactProxyURL(u.cfg.ProxyURL), "no_proxy", u.cfg.NoProxy,
+		"checksum_mask", u.cfg.ChecksumMask, "signature_mask", u.cfg.SignatureMask, "public_keys", len(u.cfg.PublicKeys),
 	)
 	return nil
 }
@@ -182,10 +302,51 @@ func (u *updateAction) findExecPaths() error {
 		path = execPath
 	}
 
+	u.execPath = strings.TrimSpace(path)
 	u.fDir = filepath.Dir(path)
-	u.fPath = strings.TrimSpace(path)
+	u.fPath = u.execPath
 	u.fName = filepath.Base(path)
-	u.fTmpPath = filepath.Join(os.TempDir(), u.fName)
+
+	// Install to the user directory instead of replacing the running binary.
+	installDir, err := u.resolveInstallDir()
+	if err != nil {
+		return err
+	}
+	if installDir != "" {
+		u.userInstall = true
+		u.fDir = filepath.Clean(installDir)
+		u.fPath = filepath.Join(u.fDir, u.fName)
+	}
+
+	// The running binary may be a version the link points to, the link must be replaced instead.
+	if u.isVersioned() && !u.userInstall {
+		if err = u.resolveLinkPath(); err != nil {
+			return err
+		}
+		if err = u.checkLinkDir(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prepareTmpDir creates a private temp directory for the downloaded binary.
+// The binary is verified and installed from it, so it can't be swapped in between
+// as it could be at a predictable path in the shared temp directory.
+func (u *updateAction) prepareTmpDir() error {
+	dir, err := os.MkdirTemp("", u.fName+"-update-*")
+	if err != nil {
+		return err
+	}
+
+	u.fTmpDir = dir
+	u.fTmpPath = filepath.Join(dir, u.fName)
+	u.fDownloadPath = u.fTmpPath
+	if u.isArchive() {
+		u.fDownloadPath = u.fTmpPath + archiveTmpSuffix
+	}
+	u.Log().Debug("prepared temp dir", "dir", dir)
 
 	return nil
 }
@@ -194,6 +355,29 @@ func (u *updateAction) findExecPaths() error {
 func (u *updateAction) getCredentials() error {
 	u.Log().Debug("get credentials for source url of release", "url", u.credentials.URL)
 
+	// Credentials from options have priority over the environment.
+	hasInput := u.credentials.Username != "" && u.credentials.Password != ""
+	if !hasInput {
+		// Credentials from the environment are never persisted.
+		envCi, err := u.envCredentials(u.credentials.URL)
+		if err != nil {
+			return err
+		}
+		if envCi.Username != "" && envCi.Password != "" {
+			u.Log().Debug("using credentials from environment", "url", u.credentials.URL)
+			u.credentials = envCi
+			return nil
+		}
+	}
+
+	// Never prompt or touch the keyring in non-interactive mode.
+	if u.nonInteractive {
+		if hasInput {
+			return nil
+		}
+		return fmt.Errorf("%w: username and password for %s, set environment variables from 'auth.username_env' and 'auth.password_env' or use --username and --password options", errNoCredentials, u.credentials.URL)
+	}
+
 	// Get credentials and save in a keyring.
 	ci, err := u.k.GetForURL(u.credentials.URL)
 	if err != nil {
@@ -213,6 +397,12 @@ func (u *updateAction) getCredentials() error {
 			}
 		}
 
+		// Nothing is persisted in check mode.
+		if u.checkOnly {
+			u.credentials = ci
+			return nil
+		}
+
 		if err = u.k.AddItem(ci); err != nil {
 			return err
 		}
@@ -227,50 +417,70 @@ func (u *updateAction) getCredentials() error {
 	return err
 }
 
-// checkAuthRequired determines if the repository requires authentication
-func (u *updateAction) checkAuthRequired(url string) (bool, error) {
-	client := &http.Client{}
+// sendRequest send HTTP request, make authorization and return response.
+func (u *updateAction) sendRequest(url string) (*http.Response, error) {
+	var resp *http.Response
+	err := u.retry(func() error {
+		var err error
+		resp, err = u.sendRangeRequest(url, 0)
+		return err
+	})
 
-	// Test with a simple HEAD request to the base repository URL
-	req, err := http.NewRequest("HEAD", url, nil)
+	return resp, err
+}
+
+// sendRangeRequest send HTTP request starting from offset, make authorization and return response.
+// Server errors are returned as retryable, other failed statuses as permanent errors.
+func (u *updateAction) sendRangeRequest(url string, offset int64) (*http.Response, error) {
+	resp, err := u.doGet(url, offset)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return false, err
+	// Negotiate auth on unauthorized response and repeat the request once.
+	an, ok := u.source.(authNegotiator)
+	if ok && resp.StatusCode == http.StatusUnauthorized && !u.authNegotiated && !u.cfg.isExplicitAuth() {
+		resp.Body.Close()
+		u.authNegotiated = true
+		if err = an.negotiateAuth(resp); err != nil {
+			return nil, &permanentError{err}
+		}
+		if resp, err = u.doGet(url, offset); err != nil {
+			return nil, err
+		}
 	}
-	defer resp.Body.Close()
 
-	u.Log().Debug("auth check response", "url", url, "status_code", resp.StatusCode)
+	if offset > 0 && resp.StatusCode == http.StatusPartialContent {
+		return resp, nil
+	}
+	if err = u.checkResponseStatus(resp); err != nil {
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return nil, &retryableError{err}
+		}
+		return nil, &permanentError{err}
+	}
 
-	// If we get 401 or 403, authentication is likely required
-	return resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden, nil
+	return resp, nil
 }
 
-// sendRequest send HTTP request, make authorization and return response.
-func (u *updateAction) sendRequest(url string) (*http.Response, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
+// doGet sends GET request prepared by the source.
+func (u *updateAction) doGet(url string, offset int64) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
-		return nil, err
+		return nil, &permanentError{err}
 	}
 
-	// Only set auth if required and we have credentials
-	if u.requiresAuth && u.credentials.Username != "" && u.credentials.Password != "" {
-		req.SetBasicAuth(u.credentials.Username, u.credentials.Password)
+	u.source.prepareRequest(req)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
 	}
-	resp, err := client.Do(req)
+	resp, err := u.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
 	u.Log().Debug("request response", "url", url, "status", resp.Status, "status_code", resp.StatusCode, "method", req.Method)
-	if err = u.checkResponseStatus(resp); err != nil {
-		return nil, err
-	}
-
 	return resp, nil
 }
 
@@ -285,23 +495,78 @@ func (u *updateAction) checkResponseStatus(r *http.Response) error {
 	case http.StatusNotFound:
 		err = fmt.Errorf("HTTP %d: Not Found. File %s does not exist", r.StatusCode, r.Request.URL.Path)
 	default:
-		err = fmt.Errorf("an issue appeared while trying to make request to %s", r.Request.URL.Path)
+		err = fmt.Errorf("HTTP %d: an issue appeared while trying to make request to %s", r.StatusCode, r.Request.URL.Path)
 	}
 
 	return err
 }
 
-// getStableRelease send request and get a stable release version.
-func (u *updateAction) getStableRelease() (string, error) {
-	vars := templateVars{
-		URL:  u.credentials.URL,
-		Name: u.appName,
-		OS:   u.os,
-		Arch: u.arch,
-		Ext:  u.ext,
+// newTemplateVars returns template variables for the given version.
+func (u *updateAction) newTemplateVars(version string) templateVars {
+	var baseURL string
+	if u.source != nil {
+		baseURL = u.source.baseURL()
 	}
+	return templateVars{
+		URL:     baseURL,
+		Name:    u.appName,
+		Version: version,
+		OS:      u.os,
+		Arch:    u.arch,
+		Ext:     u.ext,
+	}
+}
+
+// withFailover calls fn and repeats it with the next mirror of the source on failure.
+func (u *updateAction) withFailover(fn func() error) error {
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
 
-	releaseURL, err := formatURL(u.cfg.PinnedRelease, vars)
+		fs, ok := u.source.(failoverSource)
+		if !ok {
+			return err
+		}
+		failed := u.source.baseURL()
+		if !fs.nextMirror() {
+			return err
+		}
+		u.Term().Warning().Printfln("Mirror %s failed: %s. Switched to %s", failed, err, u.source.baseURL())
+	}
+}
+
+// resolveVersion returns a version to install.
+func (u *updateAction) resolveVersion() (string, error) {
+	if u.isManifestMode() {
+		m, err := u.fetchManifest()
+		if err != nil {
+			return "", err
+		}
+		if u.targetVersion != "" {
+			return u.targetVersion, nil
+		}
+		v, err := m.latestVersion()
+		if err != nil {
+			return "", err
+		}
+		u.Term().Printfln("Stable release: %s", v)
+		return v, nil
+	}
+
+	if u.targetVersion != "" {
+		// Get a specific version.
+		return u.targetVersion, nil
+	}
+
+	// Get value of Stable Release.
+	return u.source.latestVersion()
+}
+
+// getStableRelease send request and get a stable release version.
+func (u *updateAction) getStableRelease() (string, error) {
+	releaseURL, err := formatURL(u.cfg.PinnedRelease, u.newTemplateVars(""))
 	if err != nil {
 		return "", fmt.Errorf("failed to format release URL: %w", err)
 	}
@@ -326,153 +591,210 @@ func (u *updateAction) getStableRelease() (string, error) {
 
 // downloadFile Download the file using with Basic Auth header.
 func (u *updateAction) downloadFile(version string) error {
-	// Format the URL with the determined 'os', 'arch' and 'extension' values.
-	vars := templateVars{
-		URL:     u.credentials.URL,
-		Name:    u.appName,
-		Version: version,
-		OS:      u.os,
-		Arch:    u.arch,
-		Ext:     u.ext,
-	}
-
-	fileURL, err := formatURL(u.cfg.BinMask, vars)
-	if err != nil {
-		return fmt.Errorf("failed to format download URL: %w", err)
-	}
-
-	u.Term().Printfln("Downloading file: %s", fileURL)
-	resp, err := u.sendRequest(fileURL)
-	if err != nil {
+	if err := u.refreshManifestArtifact(version); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	out, err := os.Create(u.fTmpPath)
-	if err != nil {
-		return err
+	var fileURL string
+	var err error
+	if u.artifact != nil {
+		fileURL = u.artifact.URL
+	} else {
+		fileURL, err = u.source.artifactURL(version)
+		if err != nil {
+			return err
+		}
 	}
-	defer out.Close()
+	u.binURL = fileURL
 
-	if _, err = io.Copy(out, resp.Body); err != nil {
-		return err
-	}
+	u.Term().Printfln("Downloading file: %s", fileURL)
+	return u.downloadToFile(fileURL, u.fDownloadPath)
+}
 
-	fileInfo, err := os.Stat(u.fTmpPath)
+// makeExecutable adds executable permissions to the file.
+func makeExecutable(path string) error {
+	fileInfo, err := os.Stat(path)
 	if err != nil {
 		return err
 	}
 
 	fileMode := fileInfo.Mode()
 	fileMode |= 0111
-	if err = os.Chmod(u.fTmpPath, fileMode); err != nil {
-		return err
-	}
-
-	return nil
+	return os.Chmod(path, fileMode)
 }
 
-// installFile copy file to the bin folder and remove temp file.
-func (u *updateAction) installFile(dirPath string) error {
-	u.Term().Printfln("Installing %s binary under %s", u.fName, dirPath)
+// installFile copy src file of the version to the bin folder.
+// The binary is written next to the target and renamed over it, so the target is replaced atomically.
+// When the folder isn't writable, only the copy and rename are done under privilege.
+func (u *updateAction) installFile(src, version string) error {
+	u.Term().Printfln("Installing %s binary under %s", u.fName, u.fDir)
 
-	err := hasWritePermissions(dirPath)
-	sudoRequired := false
+	if u.userInstall {
+		if err := launchr.EnsurePath(u.fDir); err != nil {
+			return err
+		}
+	}
+
+	if u.isVersioned() {
+		return u.installVersioned(src, version)
+	}
+
+	err := hasWritePermissions(u.fDir)
 	if err != nil {
 		if !errors.Is(err, errNoWritePermission) {
 			return err
 		}
 
-		sudoRequired = true
+		return u.installFilePrivileged(src, u.fPath)
 	}
 
-	// Copy a temp file in the binary folder.
-	src, err := os.Open(u.fTmpPath)
+	return installFileDirect(src, u.fPath)
+}
+
+// installFileDirect copies src to a temp file next to dst and renames it over dst.
+func installFileDirect(src, dst string) (err error) {
+	srcFile, err := os.Open(filepath.Clean(src))
 	if err != nil {
 		return err
 	}
-	defer src.Close()
+	defer srcFile.Close()
 
-	if sudoRequired {
-		info, err := os.Stat(u.fDir)
+	fTmpName := dst + ".tmp"
+	tmp, err := os.OpenFile(filepath.Clean(fTmpName), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0750)
+	if err != nil {
+		return err
+	}
+	defer func() {
 		if err != nil {
-			return err
+			_ = os.Remove(fTmpName)
 		}
+	}()
 
-		pathPerm := fmt.Sprintf("%04o", info.Mode().Perm())
-		// Set temp permissions for the folder.
-		if err = u.setPermissions("777", u.fDir, sudoRequired); err != nil {
-			return err
-		}
+	if _, err = io.Copy(tmp, srcFile); err != nil {
+		_ = tmp.Close()
+		return err
+	}
 
-		defer func() {
-			if errDefer := u.setPermissions(pathPerm, u.fDir, sudoRequired); errDefer != nil {
-				u.Log().Error("error during setting folder permissions", "dir", u.fDir, "error", errDefer)
-			}
-		}()
+	if err = tmp.Close(); err != nil {
+		return err
 	}
 
-	fTmpName := u.fPath + ".tmp"
-	dst, err := os.Create(filepath.Clean(fTmpName))
-	if err != nil {
+	// Set binary permissions explicitly, the file mode on create is affected by umask.
+	if err = os.Chmod(fTmpName, 0755); err != nil { //nolint:gosec // The binary must be executable by other users.
 		return err
 	}
-	defer dst.Close()
 
-	_, err = io.Copy(dst, src)
-	if err != nil {
+	// Keep the owner of the replaced binary when running as root.
+	if err = preserveOwner(fTmpName, dst); err != nil {
 		return err
 	}
 
 	// Rename a temp file to the original binary name.
-	if err = os.Rename(fTmpName, u.fPath); err != nil {
+	return replaceBinary(fTmpName, dst)
+}
+
+// installFilePrivileged installs src to dst using the privilege escalation command.
+// The binary is placed with install(1) next to dst and then renamed over it with mv(1).
+func (u *updateAction) installFilePrivileged(src, dst string) error {
+	if err := u.checkPrivileged(installCmd); err != nil {
 		return err
 	}
 
-	// Set binary permissions.
-	if err = u.setPermissions("755", u.fPath, sudoRequired); err != nil {
+	fTmpName := dst + ".tmp"
+
+	args := []string{"-m", "0755"}
+	if uid, gid, ok := fileOwner(dst); ok {
+		args = append(args, "-o", strconv.Itoa(uid), "-g", strconv.Itoa(gid))
+	}
+
+	u.Term().Printfln("Binary folder is not writable, using %s to install the binary", u.escalationCmd)
+	if err := u.runPrivileged(installCmd, append(args, src, fTmpName)...); err != nil {
+		return err
+	}
+
+	if err := u.runPrivileged(mvCmd, "-f", fTmpName, dst); err != nil {
+		if errRm := u.runPrivileged(rmCmd, "-f", fTmpName); errRm != nil {
+			u.Log().Error("error deleting temp file", "file", fTmpName, "error", errRm)
+		}
 		return err
 	}
 
 	return nil
 }
 
-func (u *updateAction) setPermissions(permissions, target string, sudo bool) error {
-	var cmd *exec.Cmd
+// checkPrivileged resolves the privilege escalation command and checks the given commands are available.
+func (u *updateAction) checkPrivileged(cmds ...string) error {
+	// There is no sudo on windows, writing to protected folders requires an elevated process.
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("%w: %s requires administrator rights, run the update from an elevated terminal or use --install-dir to install without them", errNoWritePermission, u.fDir)
+	}
+	if os.Geteuid() == 0 {
+		return fmt.Errorf("%w: %s is not writable by root", errNoWritePermission, u.fDir)
+	}
+
+	if u.escalationCmd == "" {
+		cmd, err := getUpdateCmd(u.cfg.PrivilegeEscalation)
+		if err != nil {
+			return err
+		}
+		u.escalationCmd = cmd
+	}
 
-	if sudo {
-		if u.sudoCmd == "sudo" {
-			cmd = exec.Command(sudoCmd, chmodCmd, permissions, target)
-		} else {
-			cmd = exec.Command(doasCmd, chmodCmd, permissions, target)
+	for _, c := range cmds {
+		if ok, _ := isCommandAvailable(c); !ok {
+			return fmt.Errorf("%s is not available on your system. Please install coreutils", c)
 		}
-	} else {
-		cmd = exec.Command(chmodCmd, permissions, target)
 	}
 
-	err := cmd.Run()
-	return err
+	return nil
+}
+
+// runPrivileged runs a command with the privilege escalation command.
+func (u *updateAction) runPrivileged(name string, args ...string) error {
+	// Some escalation commands, e.g. pkexec, require an absolute path of the program.
+	if _, path := isCommandAvailable(name); path != "" {
+		name = path
+	}
+
+	cmd := exec.Command(u.escalationCmd, append([]string{name}, args...)...) //nolint:gosec // Command and arguments are controlled by the plugin.
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %w", u.escalationCmd, filepath.Base(name), err)
+	}
+
+	return nil
 }
 
 // cleanup removes temporary data.
 func (u *updateAction) cleanup() {
-	if _, err := os.Stat(u.fTmpPath); err == nil {
-		if err = os.Remove(u.fTmpPath); err != nil {
-			u.Log().Error("error deleting file", "file", u.fTmpPath, "error", err)
-		}
+	if u.fTmpDir == "" {
+		return
+	}
+	if err := os.RemoveAll(u.fTmpDir); err != nil {
+		u.Log().Error("error deleting temp dir", "dir", u.fTmpDir, "error", err)
 	}
 }
 
 // getOS return OS name and checks if it's supported.
 func getOS() (os string, err error) {
 	os = runtime.GOOS
-	if os != "linux" && os != "darwin" {
+	if os != "linux" && os != "darwin" && os != "windows" {
 		return os, errUnsupportedOS
 	}
 	os = strings.ToUpper(os[:1]) + os[1:]
 	return os, nil
 }
 
+// binaryExt returns an extension of executables on the current OS.
+func binaryExt() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
 // getArch get OS arch.
 func getArch() string {
 	arch, ok := archMap[runtime.GOARCH]
@@ -492,25 +814,22 @@ func isCommandAvailable(name string) (bool, string) {
 	return true, cmdPath
 }
 
-func getUpdateCmd() (string, error) {
-	sudoAvailable, _ := isCommandAvailable(sudoCmd)
-	doasAvailable, _ := isCommandAvailable(doasCmd)
-	chmodAvailable, _ := isCommandAvailable(chmodCmd)
-
-	if !sudoAvailable && !doasAvailable {
-		return "", fmt.Errorf("neither sudo or doas is available on your system. Please install one of them")
-	}
-
-	if !chmodAvailable {
-		return "", fmt.Errorf("chmod is not available on your system. Please install it")
-	}
-
-	var cmd string
-	if sudoAvailable {
-		cmd = sudoCmd
-	} else {
-		cmd = doasCmd
+// getUpdateCmd returns the privilege escalation command configured or the first available one.
+func getUpdateCmd(escalation string) (string, error) {
+	switch escalation {
+	case "", escalationAuto:
+		for _, cmd := range escalationCmds {
+			if ok, _ := isCommandAvailable(cmd); ok {
+				return cmd, nil
+			}
+		}
+		return "", fmt.Errorf("%w: none of %v is available, use --install-dir or install_mode: user to install without privileges", errNoWritePermission, escalationCmds)
+	case escalationNone:
+		return "", fmt.Errorf("%w: privilege escalation is disabled, use --install-dir or install_mode: user to install without privileges", errNoWritePermission)
+	default:
+		if ok, _ := isCommandAvailable(escalation); !ok {
+			return "", fmt.Errorf("%s is not available on your system. Please install it or change 'privilege_escalation'", escalation)
+		}
+		return escalation, nil
 	}
-
-	return cmd, nil
 }
@@ -0,0 +1,145 @@
+package plasmactlupdate
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/launchrctl/keyring"
+	"github.com/launchrctl/launchr"
+)
+
+func TestPrepareTmpDir(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	u := &updateAction{cfg: &config{}, fName: "app"}
+	if err := u.prepareTmpDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	if u.fTmpPath == filepath.Join(os.TempDir(), u.fName) {
+		t.Fatalf("temp path %s is predictable", u.fTmpPath)
+	}
+	if filepath.Dir(u.fTmpPath) != u.fTmpDir || filepath.Dir(u.fDownloadPath) != u.fTmpDir {
+		t.Fatalf("temp files %s, %s are not in %s", u.fTmpPath, u.fDownloadPath, u.fTmpDir)
+	}
+	fi, err := os.Stat(u.fTmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); runtime.GOOS != "windows" && perm != 0700 {
+		t.Fatalf("temp dir permissions %o, want 700", perm)
+	}
+
+	u.cleanup()
+	if _, err = os.Stat(u.fTmpDir); !os.IsNotExist(err) {
+		t.Fatalf("temp dir %s is not removed: %v", u.fTmpDir, err)
+	}
+}
+
+func TestCheckOnly(t *testing.T) {
+	srv := httptest.NewServer(serveFiles(map[string]string{
+		"/stable_release": "1.2.0",
+		"/beta_release":   "1.3.0-beta.1",
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		current  string
+		channel  string
+		wantCode int
+	}{
+		{"upgrade available", "1.0.0", "", exitCodeUpdateAvailable},
+		{"up to date", "1.2.0", "", 0},
+		{"newer installed", "1.3.0", "", 0},
+		{"upgrade in requested channel", "1.2.0", "beta", exitCodeUpdateAvailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Nothing may be written to temp and user config dirs.
+			tmp := t.TempDir()
+			home := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+
+			u := &updateAction{
+				cfg: &config{
+					RepositoryURL: srv.URL,
+					PinnedRelease: "{{.URL}}/stable_release",
+					BinMask:       "{{.URL}}/{{.Version}}/{{.Name}}_{{.OS}}_{{.Arch}}{{.Ext}}",
+					Channels:      map[string]string{"beta": "{{.URL}}/beta_release"},
+				},
+				checkOnly:      true,
+				channel:        tt.channel,
+				nonInteractive: true,
+			}
+			err := u.run(&launchr.AppVersion{Name: "app", Version: tt.current})
+			u.cleanup()
+
+			code := 0
+			if err != nil {
+				var exitErr interface{ ExitCode() int }
+				if !errors.As(err, &exitErr) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				code = exitErr.ExitCode()
+			}
+			if code != tt.wantCode {
+				t.Errorf("exit code %d, want %d", code, tt.wantCode)
+			}
+
+			for _, dir := range []string{tmp, home} {
+				entries, errRead := os.ReadDir(dir)
+				if errRead != nil {
+					t.Fatal(errRead)
+				}
+				if len(entries) != 0 {
+					t.Errorf("check mode created files in %s: %v", dir, entries)
+				}
+			}
+		})
+	}
+}
+
+func TestGetCredentialsPrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   keyring.CredentialsItem
+		env     [2]string
+		want    [2]string
+		wantErr bool
+	}{
+		{"options win over environment", keyring.CredentialsItem{Username: "cli", Password: "cli-pass"}, [2]string{"env", "env-pass"}, [2]string{"cli", "cli-pass"}, false},
+		{"environment", keyring.CredentialsItem{}, [2]string{"env", "env-pass"}, [2]string{"env", "env-pass"}, false},
+		{"incomplete options", keyring.CredentialsItem{Username: "cli"}, [2]string{"env", "env-pass"}, [2]string{"env", "env-pass"}, false},
+		{"nothing", keyring.CredentialsItem{}, [2]string{"", ""}, [2]string{"", ""}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_REPO_USERNAME", tt.env[0])
+			t.Setenv("TEST_REPO_PASSWORD", tt.env[1])
+
+			u := &updateAction{
+				cfg:            &config{Auth: authConfig{UsernameEnv: "TEST_REPO_USERNAME", PasswordEnv: "TEST_REPO_PASSWORD"}},
+				credentials:    tt.input,
+				nonInteractive: true,
+			}
+			u.credentials.URL = "https://repo.example.com"
+			err := u.getCredentials()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if got := [2]string{u.credentials.Username, u.credentials.Password}; got != tt.want {
+				t.Errorf("credentials %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package plasmactlupdate
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semver is a parsed semantic version, see https://semver.org.
+type semver struct {
+	major, minor, patch uint64
+	pre                 []string
+	build               string
+}
+
+// parseSemver parses a semantic version with an optional "v" prefix.
+func parseSemver(s string) (*semver, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	v := &semver{}
+
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		v.build = s[i+1:]
+		s = s[:i]
+		if !validIdentifiers(v.build) {
+			return nil, false
+		}
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		pre := s[i+1:]
+		s = s[:i]
+		if !validIdentifiers(pre) {
+			return nil, false
+		}
+		v.pre = strings.Split(pre, ".")
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+	nums := []*uint64{&v.major, &v.minor, &v.patch}
+	for i, p := range parts {
+		if p == "" || (len(p) > 1 && p[0] == '0') {
+			return nil, false
+		}
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		*nums[i] = n
+	}
+
+	return v, true
+}
+
+func validIdentifiers(s string) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for _, r := range id {
+			if (r < '0' || r > '9') && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && r != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compare returns -1, 0 or 1 comparing precedence of versions. Build metadata is ignored.
+func (v *semver) compare(o *semver) int {
+	if c := compareUint(v.major, o.major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.minor, o.minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.patch, o.patch); c != 0 {
+		return c
+	}
+
+	// A version without pre-release has higher precedence.
+	switch {
+	case len(v.pre) == 0 && len(o.pre) == 0:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(o.pre) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.pre) && i < len(o.pre); i++ {
+		if c := comparePreRelease(v.pre[i], o.pre[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareUint(uint64(len(v.pre)), uint64(len(o.pre)))
+}
+
+func comparePreRelease(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(an, bn)
+	case aErr == nil:
+		// Numeric identifiers have lower precedence.
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// versionChange describes a move from the current version to the target one.
+type versionChange int
+
+const (
+	changeNone versionChange = iota
+	changeUpgrade
+	changeDowngrade
+	changeReinstall
+	changeUnknown
+)
+
+func (c versionChange) String() string {
+	switch c {
+	case changeNone:
+		return "up to date"
+	case changeUpgrade:
+		return "upgrade"
+	case changeDowngrade:
+		return "downgrade"
+	case changeReinstall:
+		return "re-install"
+	default:
+		return "unknown"
+	}
+}
+
+// compareVersions determines the change from current to target version.
+//
+// Non-semver versions (e.g. "branch-hash" of local builds) can't be ordered,
+// so a change between different strings is reported as unknown and treated
+// like a downgrade.
+func compareVersions(current, target string) versionChange {
+	if current == target {
+		return changeNone
+	}
+
+	cv, okC := parseSemver(current)
+	tv, okT := parseSemver(target)
+	if !okC || !okT {
+		return changeUnknown
+	}
+
+	switch cv.compare(tv) {
+	case -1:
+		return changeUpgrade
+	case 1:
+		return changeDowngrade
+	}
+
+	// Same precedence, e.g. "v1.0.0" and "1.0.0", or different build metadata.
+	if cv.build == tv.build {
+		return changeNone
+	}
+	return changeReinstall
+}
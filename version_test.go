@@ -0,0 +1,100 @@
+package plasmactlupdate
+
+import "testing"
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		in    string
+		ok    bool
+		pre   int
+		build string
+	}{
+		{"1.2.3", true, 0, ""},
+		{"v1.2.3", true, 0, ""},
+		{" v1.2.3\n", true, 0, ""},
+		{"1.0.0-alpha.1", true, 2, ""},
+		{"1.0.0-x-y-z.--", true, 2, ""},
+		{"1.0.0+20130313144700", true, 0, "20130313144700"},
+		{"1.0.0-beta+exp.sha.5114f85", true, 1, "exp.sha.5114f85"},
+		{"1.2", false, 0, ""},
+		{"1.2.3.4", false, 0, ""},
+		{"01.2.3", false, 0, ""},
+		{"1.2.3-", false, 0, ""},
+		{"1.2.3-a..b", false, 0, ""},
+		{"1.2.3+", false, 0, ""},
+		{"1.2.3-α", false, 0, ""},
+		{"main-1a2b3c4", false, 0, ""},
+		{"latest", false, 0, ""},
+		{"", false, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			v, ok := parseSemver(tt.in)
+			if ok != tt.ok {
+				t.Fatalf("parseSemver(%q) ok = %v, want %v", tt.in, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if len(v.pre) != tt.pre || v.build != tt.build {
+				t.Fatalf("parseSemver(%q) = %+v", tt.in, v)
+			}
+		})
+	}
+}
+
+func TestSemverPrecedence(t *testing.T) {
+	// Examples from the semver specification, in ascending order.
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"2.0.0",
+		"2.1.0",
+		"2.1.1",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := parseSemver(ordered[i])
+			b, _ := parseSemver(ordered[j])
+			want := compareUint(uint64(i), uint64(j))
+			if got := a.compare(b); got != want {
+				t.Errorf("compare(%s, %s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		current, target string
+		want            versionChange
+	}{
+		{"1.2.0", "1.2.0", changeNone},
+		{"v1.2.0", "1.2.0", changeNone},
+		{"1.2.0", "1.3.0", changeUpgrade},
+		{"1.2.0", "v1.10.0", changeUpgrade},
+		{"1.0.0-rc.1", "1.0.0", changeUpgrade},
+		{"1.3.0", "1.2.9", changeDowngrade},
+		{"1.0.0", "1.0.0-rc.1", changeDowngrade},
+		{"1.2.0+build.1", "1.2.0+build.1", changeNone},
+		{"1.2.0+build.1", "1.2.0+build.2", changeReinstall},
+		{"1.2.0", "1.2.0+build.2", changeReinstall},
+		{"main-1a2b3c4", "1.2.0", changeUnknown},
+		{"1.2.0", "main-1a2b3c4", changeUnknown},
+		{"main-1a2b3c4", "main-5d6e7f8", changeUnknown},
+		{"main-1a2b3c4", "main-1a2b3c4", changeNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.current+"->"+tt.target, func(t *testing.T) {
+			if got := compareVersions(tt.current, tt.target); got != tt.want {
+				t.Fatalf("compareVersions(%q, %q) = %s, want %s", tt.current, tt.target, got, tt.want)
+			}
+		})
+	}
+}